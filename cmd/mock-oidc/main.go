@@ -0,0 +1,261 @@
+// cmd/mock-oidc/main.go
+//
+// Penyedia OIDC tiruan untuk pengembangan dan pengujian lokal login OIDC.
+// Endpoint /authorize langsung menyetujui login tanpa halaman login; identitas yang dikembalikan
+// bisa diatur lewat parameter query sub, email, email_verified dan preferred_username.
+//
+// Contoh:
+//
+//	go run ./cmd/mock-oidc -addr :9000
+//
+//	OIDC_PROVIDERS=mock
+//	OIDC_MOCK_ISSUER=http://localhost:9000
+//	OIDC_MOCK_CLIENT_ID=user-preferences
+//	OIDC_MOCK_REDIRECT_URL=http://localhost:8080/api/auth/oidc/callback
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// authCode menyimpan data yang dibutuhkan token endpoint untuk menukar authorization code
+type authCode struct {
+	ClientID      string
+	RedirectURI   string
+	CodeChallenge string
+	Nonce         string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+	ExpiresAt     time.Time
+}
+
+// mockProvider adalah penyedia OIDC tiruan dengan satu kunci RSA yang dibuat saat start
+type mockProvider struct {
+	issuer   string
+	clientID string
+	key      *rsa.PrivateKey
+	kid      string
+
+	mu    sync.Mutex
+	codes map[string]authCode
+}
+
+func main() {
+	addr := flag.String("addr", ":9000", "alamat listen")
+	issuer := flag.String("issuer", "http://localhost:9000", "URL issuer (harus sama dengan OIDC_<NAMA>_ISSUER)")
+	clientID := flag.String("client-id", "", "client ID yang diterima (kosong berarti semua)")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatalf("Failed to generate signing key: %v", err)
+	}
+
+	provider := &mockProvider{
+		issuer:   *issuer,
+		clientID: *clientID,
+		key:      key,
+		kid:      "mock-" + randomString(6),
+		codes:    make(map[string]authCode),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", provider.discoveryHandler)
+	mux.HandleFunc("/jwks", provider.jwksHandler)
+	mux.HandleFunc("/authorize", provider.authorizeHandler)
+	mux.HandleFunc("/token", provider.tokenHandler)
+
+	log.Printf("Mock OIDC provider %s listening on %s", *issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+// discoveryHandler mengembalikan dokumen discovery OIDC
+func (p *mockProvider) discoveryHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "none"},
+	})
+}
+
+// jwksHandler mengembalikan kunci publik penanda tangan ID token
+func (p *mockProvider) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": p.kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+// authorizeHandler langsung menyetujui login dan me-redirect ke redirect_uri dengan code dan state
+func (p *mockProvider) authorizeHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("response_type") != "code" {
+		http.Error(w, "unsupported response_type", http.StatusBadRequest)
+		return
+	}
+	if p.clientID != "" && query.Get("client_id") != p.clientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+	if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirectURI.Host == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	subject := valueOr(query.Get("sub"), "mock-user-1")
+	code := randomString(24)
+
+	p.mu.Lock()
+	p.codes[code] = authCode{
+		ClientID:      query.Get("client_id"),
+		RedirectURI:   query.Get("redirect_uri"),
+		CodeChallenge: query.Get("code_challenge"),
+		Nonce:         query.Get("nonce"),
+		Subject:       subject,
+		Email:         valueOr(query.Get("email"), subject+"@example.com"),
+		EmailVerified: query.Get("email_verified") != "false",
+		Username:      query.Get("preferred_username"),
+		ExpiresAt:     time.Now().Add(time.Minute),
+	}
+	p.mu.Unlock()
+
+	params := redirectURI.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirectURI.RawQuery = params.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+// tokenHandler menukar authorization code (dengan PKCE verifier) menjadi ID token bertanda tangan
+func (p *mockProvider) tokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, "invalid_request", "malformed form body")
+		return
+	}
+
+	clientID := r.PostForm.Get("client_id")
+	if user, _, ok := r.BasicAuth(); ok {
+		clientID, _ = url.QueryUnescape(user)
+	}
+
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	grant, ok := p.codes[code]
+	delete(p.codes, code) // code hanya bisa dipakai sekali
+	p.mu.Unlock()
+
+	switch {
+	case r.PostForm.Get("grant_type") != "authorization_code":
+		writeTokenError(w, "unsupported_grant_type", "")
+		return
+	case !ok || time.Now().After(grant.ExpiresAt):
+		writeTokenError(w, "invalid_grant", "unknown or expired code")
+		return
+	case clientID != grant.ClientID:
+		writeTokenError(w, "invalid_client", "client_id does not match the authorization request")
+		return
+	case r.PostForm.Get("redirect_uri") != grant.RedirectURI:
+		writeTokenError(w, "invalid_grant", "redirect_uri does not match the authorization request")
+		return
+	}
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(verifier[:]) != grant.CodeChallenge {
+		writeTokenError(w, "invalid_grant", "PKCE verification failed")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.issuer,
+		"sub":            grant.Subject,
+		"aud":            grant.ClientID,
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          grant.Nonce,
+		"email":          grant.Email,
+		"email_verified": grant.EmailVerified,
+	}
+	if grant.Username != "" {
+		claims["preferred_username"] = grant.Username
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.kid
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		http.Error(w, "failed to sign id token", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(24),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func writeTokenError(w http.ResponseWriter, code, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("Failed to generate random bytes: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -4,7 +4,6 @@ package config
 import (
 	"fmt"
 	"log"
-	"os"
 
 	"main/models"
 
@@ -15,6 +14,19 @@ import (
 
 var DB *gorm.DB
 
+// DatabaseDSN membuat string koneksi DSN dari environment variable. Dipakai juga oleh koneksi
+// terpisah di luar GORM (mis. LISTEN/NOTIFY).
+func DatabaseDSN() string {
+	dbHost := GetEnv("DB_HOST", "localhost")
+	dbUser := GetEnv("DB_USER", "postgres")
+	dbPassword := GetEnv("DB_PASSWORD", "password")
+	dbName := GetEnv("DB_NAME", "userpreferences_db")
+	dbPort := GetEnv("DB_PORT", "5432")
+
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Jakarta",
+		dbHost, dbUser, dbPassword, dbName, dbPort)
+}
+
 // InitDatabase menginisialisasi koneksi ke database
 func InitDatabase() {
 	// Load environment variables dari .env file
@@ -23,18 +35,8 @@ func InitDatabase() {
 		log.Println("Error loading .env file, using environment variables")
 	}
 
-	dbHost := getEnv("DB_HOST", "localhost")
-	dbUser := getEnv("DB_USER", "postgres")
-	dbPassword := getEnv("DB_PASSWORD", "password")
-	dbName := getEnv("DB_NAME", "userpreferences_db")
-	dbPort := getEnv("DB_PORT", "5432")
-
-	// Membuat string koneksi DSN
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Jakarta",
-		dbHost, dbUser, dbPassword, dbName, dbPort)
-
 	// Membuka koneksi ke database
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(DatabaseDSN()), &gorm.Config{})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -43,19 +45,56 @@ func InitDatabase() {
 	DB = db
 
 	// Migrate models ke database
-	err = DB.AutoMigrate(&models.User{}, &models.UserPreferences{})
+	err = DB.AutoMigrate(
+		&models.User{},
+		&models.UserPreferences{},
+		&models.PreferenceValue{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.UserTokenRevocation{},
+		&models.PasswordResetToken{},
+		&models.RecoveryCode{},
+		&models.LoginAttempt{},
+		&models.APIToken{},
+		&models.ExternalIdentity{},
+		&models.OIDCAuthRequest{},
+		&models.WebAuthnCredential{},
+		&models.WebAuthnChallenge{},
+		&models.UsedMagicLink{},
+		&models.Session{},
+		&models.DataExport{},
+		&models.PreferenceHistory{},
+	)
 	if err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
-	log.Println("Database migration completed")
-}
+	// Token unduh ekspor sempat disimpan mentah; yang disimpan cukup hash-nya
+	if DB.Migrator().HasColumn(&models.DataExport{}, "download_token") {
+		if err := DB.Migrator().DropColumn(&models.DataExport{}, "download_token"); err != nil {
+			log.Fatalf("Failed to drop data_exports.download_token: %v", err)
+		}
+	}
+
+	// Pindahkan nilai preferensi dari kolom lama ke preference_values
+	if err := MigrateLegacyPreferences(); err != nil {
+		log.Fatalf("Failed to migrate preferences: %v", err)
+	}
+
+	// Perbaiki nilai preferensi lama yang tidak lolos validasi (mis. language "EN")
+	if err := NormalizeStoredPreferences(); err != nil {
+		log.Fatalf("Failed to normalize preferences: %v", err)
+	}
 
-// getEnv mendapatkan nilai dari environment variable atau menggunakan nilai default
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+	// Samakan versi preferensi dengan riwayat perubahan yang sudah ada
+	if err := MigratePreferenceVersions(); err != nil {
+		log.Fatalf("Failed to migrate preference versions: %v", err)
 	}
-	return value
+
+	// Username dan email unik tanpa membedakan huruf besar/kecil
+	if _, err := MigrateCanonicalIdentities(); err != nil {
+		log.Fatalf("Failed to migrate canonical identities: %v", err)
+	}
+
+	log.Println("Database migration completed")
 }
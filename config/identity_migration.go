@@ -0,0 +1,118 @@
+// config/identity_migration.go
+package config
+
+import (
+	"fmt"
+	"log"
+
+	"main/models"
+	"main/utils"
+
+	"gorm.io/gorm"
+)
+
+// IdentityCollision adalah sekelompok akun yang username atau email kanoniknya sama,
+// misalnya "Alice" dan "alice", sehingga unique index tanpa membedakan huruf besar/kecil belum bisa dibuat
+type IdentityCollision struct {
+	Column  string
+	Value   string
+	UserIDs []uint
+}
+
+// canonicalIdentityColumns memetakan kolom kanonik ke nama unique index-nya
+var canonicalIdentityColumns = []struct{ column, index string }{
+	{"username_canonical", "idx_users_username_canonical"},
+	{"email_canonical", "idx_users_email_canonical"},
+}
+
+// MigrateCanonicalIdentities mengisi kolom username/email kanonik untuk akun lama, lalu membuat unique index-nya.
+// Akun yang dihapus (soft delete) ikut diperiksa karena username dan emailnya tetap terpakai sampai dihapus permanen.
+// Jika ada tabrakan, index untuk kolom itu tidak dibuat dan setiap tabrakan dicatat di log; selesaikan dengan
+// mengganti username atau email salah satu akun, lalu jalankan ulang aplikasi.
+func MigrateCanonicalIdentities() ([]IdentityCollision, error) {
+	if err := backfillCanonicalIdentities(); err != nil {
+		return nil, err
+	}
+
+	collisions, err := FindIdentityCollisions()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, target := range canonicalIdentityColumns {
+		blocked := false
+		for _, collision := range collisions {
+			if collision.Column == target.column {
+				blocked = true
+				log.Printf("Identity collision: users %v share %s %q", collision.UserIDs, collision.Column, collision.Value)
+			}
+		}
+		if blocked {
+			log.Printf("Unique index %s was not created; resolve the collisions above and restart", target.index)
+			continue
+		}
+
+		sql := fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS %s ON users (%s)", target.index, target.column)
+		if err := DB.Exec(sql).Error; err != nil {
+			return collisions, err
+		}
+	}
+	return collisions, nil
+}
+
+// FindIdentityCollisions mencari akun yang username atau email kanoniknya sama
+func FindIdentityCollisions() ([]IdentityCollision, error) {
+	var collisions []IdentityCollision
+	for _, target := range canonicalIdentityColumns {
+		var values []string
+		err := DB.Unscoped().Model(&models.User{}).
+			Where(target.column+" <> ''").
+			Group(target.column).
+			Having("COUNT(*) > 1").
+			Pluck(target.column, &values).Error
+		if err != nil {
+			return nil, err
+		}
+
+		for _, value := range values {
+			var userIDs []uint
+			err := DB.Unscoped().Model(&models.User{}).
+				Where(target.column+" = ?", value).
+				Order("id").
+				Pluck("id", &userIDs).Error
+			if err != nil {
+				return nil, err
+			}
+			collisions = append(collisions, IdentityCollision{Column: target.column, Value: value, UserIDs: userIDs})
+		}
+	}
+	return collisions, nil
+}
+
+// backfillCanonicalIdentities mengisi kolom kanonik yang masih kosong (akun dari sebelum kolom ini ada)
+func backfillCanonicalIdentities() error {
+	var users []models.User
+	result := DB.Unscoped().
+		Select("id", "username", "email").
+		Where("username_canonical IS NULL OR username_canonical = '' OR email_canonical IS NULL OR email_canonical = ''").
+		FindInBatches(&users, 500, func(tx *gorm.DB, batch int) error {
+			for _, user := range users {
+				// UpdateColumns melewati hook dan updated_at: ini hanya pengisian kolom turunan
+				err := DB.Unscoped().Model(&models.User{}).Where("id = ?", user.ID).UpdateColumns(map[string]interface{}{
+					"username_canonical": utils.CanonicalIdentifier(user.Username),
+					"email_canonical":    utils.CanonicalIdentifier(user.Email),
+				}).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return fmt.Errorf("backfill canonical identities: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Filled canonical username/email for %d user(s)", result.RowsAffected)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+// config/preference_migration.go
+package config
+
+import (
+	"encoding/json"
+	"log"
+
+	"main/models"
+
+	"gorm.io/gorm"
+)
+
+// legacyPreferenceColumns adalah kolom tetap di user_preferences sebelum ada registry preferensi
+var legacyPreferenceColumns = []string{"theme", "language", "notifications"}
+
+// MigrateLegacyPreferences menyalin nilai dari kolom theme, language dan notifications di user_preferences
+// ke tabel preference_values (sebagai JSON), lalu menghapus kolom lama. Nilai yang sudah ada di
+// preference_values tidak ditimpa, sehingga migrasi aman dijalankan ulang jika sempat terhenti.
+func MigrateLegacyPreferences() error {
+	migrator := DB.Migrator()
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		migrated := int64(0)
+		for _, column := range legacyPreferenceColumns {
+			if !migrator.HasColumn(&models.UserPreferences{}, column) {
+				continue
+			}
+
+			// Nama kolom sama dengan key preferensi bawaan
+			result := tx.Exec(`
+				INSERT INTO preference_values (user_id, key, value, created_at, updated_at)
+				SELECT user_id, ?, to_json(`+column+`)::text, updated_at, updated_at
+				FROM user_preferences
+				WHERE `+column+` IS NOT NULL
+				ON CONFLICT (user_id, key) DO NOTHING`, column)
+			if result.Error != nil {
+				return result.Error
+			}
+			migrated += result.RowsAffected
+
+			if err := tx.Migrator().DropColumn(&models.UserPreferences{}, column); err != nil {
+				return err
+			}
+		}
+
+		if migrated > 0 {
+			log.Printf("Migrated %d preference value(s) from legacy columns", migrated)
+		}
+		return nil
+	})
+}
+
+// NormalizeStoredPreferences memeriksa ulang nilai di preference_values terhadap registry preferensi.
+// Nilai yang bisa dinormalisasi (mis. language "EN" menjadi "english") ditulis ulang dalam bentuk kanonik,
+// sedangkan nilai yang tidak valid (mis. theme "purple") dihapus sehingga pengguna kembali memakai nilai default.
+// Key yang tidak terdaftar dibiarkan agar tidak hilang jika versi yang mendaftarkannya dipasang kembali.
+// Baris diproses per batch agar startup tidak memuat seluruh tabel ke memori.
+func NormalizeStoredPreferences() error {
+	var keys []string
+	for _, def := range models.PreferenceDefinitions() {
+		keys = append(keys, def.Key)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	rewritten, removed := 0, 0
+	var values []models.PreferenceValue
+	result := DB.Where("key IN ?", keys).FindInBatches(&values, 500, func(tx *gorm.DB, batch int) error {
+		for _, stored := range values {
+			def, ok := models.LookupPreference(stored.Key)
+			if !ok {
+				continue
+			}
+
+			value, err := def.Decode(stored.Value)
+			if err != nil {
+				log.Printf("Removing invalid preference %s=%s for user %d: %v", stored.Key, stored.Value, stored.UserID, err)
+				if err := DB.Delete(&stored).Error; err != nil {
+					return err
+				}
+				removed++
+				continue
+			}
+
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			if string(encoded) != stored.Value {
+				if err := DB.Model(&stored).Update("value", string(encoded)).Error; err != nil {
+					return err
+				}
+				rewritten++
+			}
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if rewritten > 0 || removed > 0 {
+		log.Printf("Normalized %d and removed %d stored preference value(s)", rewritten, removed)
+	}
+	return nil
+}
+
+// MigratePreferenceVersions mengisi kolom version di user_preferences dari versi terakhir di riwayat
+// untuk baris yang dibuat sebelum kolom tersebut ada. Aman dijalankan berulang kali.
+func MigratePreferenceVersions() error {
+	result := DB.Exec(`
+		UPDATE user_preferences p
+		SET version = h.version
+		FROM (SELECT user_id, MAX(version) AS version FROM preference_histories GROUP BY user_id) h
+		WHERE h.user_id = p.user_id AND p.version < h.version`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Backfilled preference version for %d user(s)", result.RowsAffected)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+// handlers/account_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"main/config"
+	"main/models"
+	"main/services"
+)
+
+// ChangePasswordRequest merupakan struktur untuk permintaan ganti password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ChangeEmailRequest merupakan struktur untuk permintaan ganti email
+type ChangeEmailRequest struct {
+	Password string `json:"password"`
+	Email    string `json:"email"`
+}
+
+// DeleteAccountRequest merupakan struktur untuk permintaan hapus akun
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// DeleteAccountResponse berisi waktu akun akan dihapus permanen
+type DeleteAccountResponse struct {
+	Message string    `json:"message"`
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// RestoreAccountRequest merupakan struktur untuk permintaan pemulihan akun
+type RestoreAccountRequest struct {
+	Token string `json:"token"`
+}
+
+// ChangePasswordHandler mengganti password pengguna dan mencabut semua sesi lain
+func ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	// Parse request body
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		http.Error(w, "Current password and new password are required", http.StatusBadRequest)
+		return
+	}
+
+	pair, err := services.ChangePassword(userID, req.CurrentPassword, req.NewPassword, sessionMetadata(r))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPassword) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if writeValidationError(w, err) {
+			return
+		}
+		http.Error(w, "Failed to change password", http.StatusInternalServerError)
+		return
+	}
+
+	// Load pengguna dengan preferensi untuk response
+	var user models.User
+	result := config.DB.Preload("Preferences.Values").First(&user, userID)
+	if result.Error != nil {
+		http.Error(w, "Failed to get user: "+result.Error.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Kirim response berisi token baru karena token lama sudah dicabut
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newAuthResponse(pair, user))
+}
+
+// ChangeEmailHandler menyimpan email baru sebagai email tertunda dan mengirim tautan verifikasi ke alamat tersebut
+func ChangeEmailHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	// Parse request body
+	var req ChangeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Password == "" || req.Email == "" {
+		http.Error(w, "Password and email are required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := services.ChangeEmail(userID, req.Password, req.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidPassword):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, services.ErrInvalidEmail):
+			http.Error(w, "Invalid email address", http.StatusBadRequest)
+		case errors.Is(err, services.ErrEmailTaken):
+			http.Error(w, "Username or email already exists", http.StatusConflict)
+		default:
+			http.Error(w, "Failed to change email", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// DeleteAccountHandler menjadwalkan penghapusan akun pengguna setelah password dikonfirmasi.
+// Akun bisa dipulihkan lewat tautan di email selama masa tenggang.
+func DeleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	// Parse request body
+	var req DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Password == "" {
+		http.Error(w, "Password is required", http.StatusBadRequest)
+		return
+	}
+
+	purgeAt, err := services.DeleteAccount(userID, req.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidPassword):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, services.ErrPasswordNotSet):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(DeleteAccountResponse{
+		Message: "Account scheduled for deletion. Use the link sent by email to restore it before it is permanently deleted.",
+		PurgeAt: purgeAt,
+	})
+}
+
+// RestoreAccountHandler membatalkan penghapusan akun dari tautan pemulihan lalu melanjutkan login
+func RestoreAccountHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var req RestoreAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := services.RestoreAccount(req.Token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRestoreToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to restore account", http.StatusInternalServerError)
+		return
+	}
+
+	if user.Disabled {
+		http.Error(w, "Account is disabled", http.StatusForbidden)
+		return
+	}
+
+	completeLogin(w, r, *user)
+}
@@ -0,0 +1,274 @@
+// handlers/admin_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"main/config"
+	"main/models"
+	"main/services"
+
+	"github.com/gorilla/mux"
+)
+
+// UnlockLoginRequest merupakan struktur untuk membuka penguncian login (username dan/atau IP)
+type UnlockLoginRequest struct {
+	Username string `json:"username"`
+	IP       string `json:"ip"`
+}
+
+// SetRoleRequest merupakan struktur untuk mengganti role pengguna
+type SetRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// AdminListUsersHandler menampilkan dan mencari pengguna (?q=, ?page=, ?page_size=)
+func AdminListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	pageSize, _ := strconv.Atoi(query.Get("page_size"))
+
+	result, err := services.ListUsers(query.Get("q"), page, pageSize)
+	if err != nil {
+		http.Error(w, "Failed to list users: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// AdminGetUserHandler menampilkan satu pengguna beserta preferensinya
+func AdminGetUserHandler(w http.ResponseWriter, r *http.Request) {
+	targetID, ok := parseUserIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := services.GetUser(targetID)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// AdminDisableUserHandler menonaktifkan akun pengguna dan mencabut semua sesinya
+func AdminDisableUserHandler(w http.ResponseWriter, r *http.Request) {
+	setUserDisabled(w, r, true)
+}
+
+// AdminEnableUserHandler mengaktifkan kembali akun pengguna
+func AdminEnableUserHandler(w http.ResponseWriter, r *http.Request) {
+	setUserDisabled(w, r, false)
+}
+
+// AdminSetRoleHandler mengganti role pengguna
+func AdminSetRoleHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID admin dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	targetID, ok := parseUserIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req SetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if targetID == userID {
+		http.Error(w, "You cannot change your own role", http.StatusBadRequest)
+		return
+	}
+
+	user, err := services.SetUserRole(targetID, req.Role)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// AdminDeleteUserHandler menghapus permanen pengguna beserta semua datanya
+func AdminDeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID admin dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	targetID, ok := parseUserIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if targetID == userID {
+		http.Error(w, "You cannot delete your own account from the admin API", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.DeleteUser(targetID); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdminGetPreferencesHandler menampilkan preferensi milik pengguna mana pun
+func AdminGetPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	targetID, ok := parseUserIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	// Ambil preferensi dari database
+	preferences, err := services.GetPreferences(targetID)
+	if err != nil {
+		writePreferenceError(w, err)
+		return
+	}
+
+	// Kirim respons
+	writePreferences(w, preferences)
+}
+
+// AdminUpdatePreferencesHandler memperbarui preferensi milik pengguna mana pun
+func AdminUpdatePreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID admin dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	targetID, ok := parseUserIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req UpdatePreferenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validasi dan simpan perubahan
+	preferences, err := services.ApplyPreferenceChanges(targetID, req, services.PreferenceChangeContext{
+		Source:  models.PreferenceSourceAdmin,
+		ActorID: userID,
+		IfMatch: r.Header.Get("If-Match"),
+	})
+	if err != nil {
+		writePreferenceError(w, err)
+		return
+	}
+
+	// Kirim respons
+	writePreferences(w, preferences)
+}
+
+// AdminPreferenceHistoryHandler menampilkan riwayat perubahan preferensi milik pengguna mana pun
+// (?key=, ?page=, ?page_size=)
+func AdminPreferenceHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	targetID, ok := parseUserIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	writePreferenceHistory(w, r, targetID)
+}
+
+// UnlockLoginHandler membuka penguncian login untuk akun (username atau email) dan/atau alamat IP
+func UnlockLoginHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var req UnlockLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var keys []string
+	if req.Username != "" {
+		// Kunci akun (dicari lewat username atau email) dan kunci identifier mentah untuk akun yang tidak dikenal
+		keys = append(keys, services.LoginKey(req.Username, nil))
+		user, err := services.FindUserByIdentifier(config.DB, req.Username)
+		if err != nil && !errors.Is(err, services.ErrUserNotFound) {
+			http.Error(w, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+		if user != nil {
+			keys = append(keys, services.LoginKey(req.Username, user))
+		}
+	}
+	if req.IP != "" {
+		keys = append(keys, services.IPKey(req.IP))
+	}
+	if len(keys) == 0 {
+		http.Error(w, "Username or IP is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.DefaultLoginLimiter().Unlock(keys...); err != nil {
+		http.Error(w, "Failed to unlock login", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setUserDisabled mengubah status nonaktif akun pengguna target
+func setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	// Dapatkan ID admin dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	targetID, ok := parseUserIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if targetID == userID {
+		http.Error(w, "You cannot disable your own account", http.StatusBadRequest)
+		return
+	}
+
+	user, err := services.SetUserDisabled(targetID, disabled)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// parseUserIDParam membaca {id} dari path; mengirim 400 dan mengembalikan false jika tidak valid
+func parseUserIDParam(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writeAdminError memetakan error layanan admin ke status HTTP
+func writeAdminError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidRole):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Failed to process request: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,185 @@
+// handlers/api_token_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"main/models"
+	"main/services"
+
+	"github.com/gorilla/mux"
+)
+
+// CreateAPITokenRequest merupakan struktur untuk membuat API token baru
+type CreateAPITokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // opsional; kosong berarti tidak kedaluwarsa
+}
+
+// UpdateAPITokenRequest merupakan struktur untuk memperbarui nama atau scope API token
+type UpdateAPITokenRequest struct {
+	Name   *string  `json:"name,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// CreateAPITokenResponse berisi token mentah (hanya ditampilkan sekali) beserta metadata-nya
+type CreateAPITokenResponse struct {
+	Token    string          `json:"token"`
+	APIToken models.APIToken `json:"api_token"`
+}
+
+// ListAPITokensResponse merupakan struktur untuk daftar API token
+type ListAPITokensResponse struct {
+	Tokens []models.APIToken `json:"tokens"`
+}
+
+// ListAPITokensHandler menampilkan semua API token milik pengguna
+func ListAPITokensHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	tokens, err := services.ListAPITokens(userID)
+	if err != nil {
+		http.Error(w, "Failed to get API tokens: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ListAPITokensResponse{
+		Tokens: tokens,
+	})
+}
+
+// CreateAPITokenHandler membuat API token baru untuk pengguna
+func CreateAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	// Parse request body
+	var req CreateAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" || len(req.Scopes) == 0 {
+		http.Error(w, "Name and at least one scope are required", http.StatusBadRequest)
+		return
+	}
+
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		http.Error(w, "Expiry must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	rawToken, token, err := services.CreateAPIToken(userID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidScope) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to create API token", http.StatusInternalServerError)
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateAPITokenResponse{
+		Token:    rawToken,
+		APIToken: *token,
+	})
+}
+
+// GetAPITokenHandler menampilkan metadata satu API token
+func GetAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	tokenID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	token, err := services.GetAPIToken(userID, uint(tokenID))
+	if err != nil {
+		writeAPITokenError(w, err)
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(token)
+}
+
+// UpdateAPITokenHandler mengganti nama atau scope API token
+func UpdateAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	tokenID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	// Parse request body
+	var req UpdateAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name != nil && *req.Name == "" {
+		http.Error(w, "Name cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	token, err := services.UpdateAPIToken(userID, uint(tokenID), req.Name, req.Scopes)
+	if err != nil {
+		writeAPITokenError(w, err)
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(token)
+}
+
+// DeleteAPITokenHandler mencabut API token
+func DeleteAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	tokenID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.DeleteAPIToken(userID, uint(tokenID)); err != nil {
+		writeAPITokenError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeAPITokenError memetakan error layanan API token ke status HTTP
+func writeAPITokenError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrAPITokenNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidScope):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Failed to process API token: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -3,11 +3,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"main/config"
 	"main/models"
+	"main/services"
 	"main/utils"
 )
 
@@ -18,16 +24,43 @@ type RegisterRequest struct {
 	Password string `json:"password"`
 }
 
-// LoginRequest merupakan struktur untuk permintaan login
+// LoginRequest merupakan struktur untuk permintaan login. Identifier boleh berisi username atau email;
+// field username dan email tetap diterima untuk klien lama.
 type LoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Identifier string `json:"identifier"`
+	Username   string `json:"username"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+}
+
+// identifier mengembalikan identitas login dari field pertama yang diisi
+func (req LoginRequest) identifier() string {
+	for _, value := range []string{req.Identifier, req.Username, req.Email} {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
 }
 
 // AuthResponse merupakan struktur untuk respons autentikasi
 type AuthResponse struct {
-	Token string      `json:"token"`
-	User  models.User `json:"user"`
+	Token                 string      `json:"token"`
+	ExpiresAt             time.Time   `json:"expires_at"`
+	RefreshToken          string      `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time   `json:"refresh_token_expires_at"`
+	User                  models.User `json:"user"`
+}
+
+// newAuthResponse menyusun AuthResponse dari pasangan token yang diterbitkan
+func newAuthResponse(pair *services.TokenPair, user models.User) AuthResponse {
+	return AuthResponse{
+		Token:                 pair.AccessToken,
+		ExpiresAt:             pair.AccessTokenExpiresAt,
+		RefreshToken:          pair.RefreshToken,
+		RefreshTokenExpiresAt: pair.RefreshTokenExpiresAt,
+		User:                  user,
+	}
 }
 
 // RegisterHandler menangani permintaan registrasi pengguna baru
@@ -46,9 +79,33 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Cek apakah username atau email sudah digunakan
+	if err := services.ValidateEmailAddress(req.Email); err != nil {
+		http.Error(w, "Invalid email address", http.StatusBadRequest)
+		return
+	}
+
+	// Periksa password terhadap kebijakan password
+	pctx := services.PasswordContext{Username: req.Username, Email: req.Email}
+	if err := services.ValidatePassword("password", req.Password, pctx); err != nil {
+		if !writeValidationError(w, err) {
+			http.Error(w, "Failed to validate password: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// "@" hanya boleh ada di email agar identifier login tidak ambigu
+	if strings.Contains(req.Username, "@") {
+		http.Error(w, "Username must not contain @", http.StatusBadRequest)
+		return
+	}
+
+	// Cek apakah username atau email sudah digunakan sebagai username maupun email (tanpa membedakan
+	// huruf besar/kecil), termasuk oleh akun yang menunggu penghapusan permanen
+	identifiers := []string{utils.CanonicalIdentifier(req.Username), utils.CanonicalIdentifier(req.Email)}
 	var existingUser models.User
-	result := config.DB.Where("username = ? OR email = ?", req.Username, req.Email).First(&existingUser)
+	result := config.DB.Unscoped().
+		Where("username_canonical IN ? OR email_canonical IN ?", identifiers, identifiers).
+		First(&existingUser)
 	if result.RowsAffected > 0 {
 		http.Error(w, "Username or email already exists", http.StatusConflict)
 		return
@@ -58,10 +115,13 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	user := models.User{
 		Username:  req.Username,
 		Email:     req.Email,
-		Password:  req.Password,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
+	if err := user.SetPassword(req.Password); err != nil {
+		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+		return
+	}
 
 	// Simpan user ke database
 	result = config.DB.Create(&user)
@@ -71,14 +131,7 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Buat preferensi default untuk user
-	preferences := models.UserPreferences{
-		UserID:        user.ID,
-		Theme:         "light",
-		Language:      "english",
-		Notifications: true,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-	}
+	preferences := models.DefaultPreferences(user.ID)
 
 	// Simpan preferensi ke database
 	result = config.DB.Create(&preferences)
@@ -87,23 +140,25 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(user.ID)
+	// Kirim tautan verifikasi email (kegagalan pengiriman tidak membatalkan registrasi)
+	if err := services.SendVerificationEmail(user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+
+	// Terbitkan access token dan refresh token
+	pair, err := services.IssueTokens(user.ID, sessionMetadata(r))
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
 
-	// Load preferensi untuk response
-	config.DB.Model(&user).Association("Preferences").Find(&user.Preferences)
+	// Pengguna baru memakai preferensi default
+	user.Preferences = preferences
 
 	// Kirim response
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(AuthResponse{
-		Token: token,
-		User:  user,
-	})
+	json.NewEncoder(w).Encode(newAuthResponse(pair, user))
 }
 
 // LoginHandler menangani permintaan login pengguna
@@ -117,39 +172,122 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validasi input
-	if req.Username == "" || req.Password == "" {
-		http.Error(w, "Username and password are required", http.StatusBadRequest)
+	identifier := req.identifier()
+	if identifier == "" || req.Password == "" {
+		http.Error(w, "Username or email and password are required", http.StatusBadRequest)
 		return
 	}
 
-	// Cari user di database
+	// Cari user berdasarkan username atau email (tanpa membedakan huruf besar/kecil)
 	var user models.User
-	result := config.DB.Where("username = ?", req.Username).First(&user)
-	if result.RowsAffected == 0 {
-		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+	found, err := services.FindUserByIdentifier(config.DB, identifier)
+	if err != nil && !errors.Is(err, services.ErrUserNotFound) {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
 		return
 	}
 
-	// Cek password
-	if !user.CheckPassword(req.Password) {
-		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+	// Catat percobaan untuk akun maupun IP sebelum password diperiksa, sehingga permintaan bersamaan
+	// tidak bisa melewati penguncian; tolak selama masa backoff/penguncian
+	limiter := services.DefaultLoginLimiter()
+	userKey := services.LoginKey(identifier, found)
+	ipKey := services.IPKey(utils.ClientIP(r))
+	wait, err := limiter.Attempt(userKey, ipKey)
+	if err != nil {
+		http.Error(w, "Failed to check login attempts", http.StatusInternalServerError)
 		return
 	}
+	if wait > 0 {
+		writeTooManyRequests(w, wait)
+		return
+	}
+
+	// Cek password. Akun yang tidak ada (atau tanpa password) tetap melewati hashing agar waktu respons
+	// tidak membocorkan username dan email yang terdaftar.
+	passwordOK, needsRehash := false, false
+	if found != nil && found.Password != "" {
+		user = *found
+		passwordOK, needsRehash = user.VerifyPassword(req.Password)
+	} else {
+		utils.VerifyDummyPassword(req.Password)
+	}
+	if !passwordOK {
+		if _, err := limiter.Failed(userKey, ipKey); err != nil {
+			log.Printf("Failed to record login attempt: %v", err)
+		}
+		http.Error(w, "Invalid username, email or password", http.StatusUnauthorized)
+		return
+	}
+
+	// Login berhasil: hapus riwayat gagal akun dan batalkan hitungan percobaan ini pada IP
+	if err := limiter.Succeed(userKey); err != nil {
+		log.Printf("Failed to reset login attempts: %v", err)
+	}
+	if err := limiter.Release(ipKey); err != nil {
+		log.Printf("Failed to release login attempt: %v", err)
+	}
+
+	// Hash lama (bcrypt atau parameter Argon2id lama) di-hash ulang selagi password asli tersedia
+	if needsRehash {
+		if err := services.UpgradePasswordHash(&user, req.Password); err != nil {
+			log.Printf("Failed to upgrade password hash for user %d: %v", user.ID, err)
+		}
+	}
+
+	// Akun yang dinonaktifkan admin tidak bisa login meskipun password benar
+	if user.Disabled {
+		http.Error(w, "Account is disabled", http.StatusForbidden)
+		return
+	}
+
+	completeLogin(w, r, user)
+}
+
+// writeTooManyRequests mengirim 429 dengan header Retry-After (dalam detik, dibulatkan ke atas)
+func writeTooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, "Too many failed attempts, try again later", http.StatusTooManyRequests)
+}
+
+// completeLogin menyelesaikan login setelah faktor pertama berhasil: jika 2FA aktif,
+// kirim token "mfa pending"; jika tidak, terbitkan token sesi penuh
+func completeLogin(w http.ResponseWriter, r *http.Request, user models.User) {
+	if user.TOTPEnabled {
+		mfaToken, expiresAt, err := services.CreateMFAPendingToken(user.ID)
+		if err != nil {
+			http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			ExpiresAt:   expiresAt,
+		})
+		return
+	}
+
+	writeSession(w, r, user)
+}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(user.ID)
+// writeSession membuat sesi untuk perangkat yang login, menerbitkan access token dan refresh token,
+// lalu mengirim AuthResponse
+func writeSession(w http.ResponseWriter, r *http.Request, user models.User) {
+	// Terbitkan access token dan refresh token
+	pair, err := services.IssueTokens(user.ID, sessionMetadata(r))
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
 
 	// Load preferensi untuk response
-	config.DB.Preload("Preferences").First(&user, user.ID)
+	config.DB.Preload("Preferences.Values").First(&user, user.ID)
 
 	// Kirim response
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(AuthResponse{
-		Token: token,
-		User:  user,
-	})
+	json.NewEncoder(w).Encode(newAuthResponse(pair, user))
 }
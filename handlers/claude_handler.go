@@ -3,11 +3,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strings"
+	"unicode"
 
-	"main/config"
 	"main/models"
+	"main/services"
 )
 
 // ClaudeRequest merupakan struktur untuk permintaan ke Claude Desktop
@@ -22,7 +24,15 @@ type ClaudeResponse struct {
 	Action      string                  `json:"action,omitempty"`
 }
 
-// ClaudeHandler menangani permintaan ke Claude Desktop
+// Kata untuk menyalakan/mematikan preferensi boolean dan untuk menanyakan nilai saat ini
+var (
+	assistantOnWords    = []string{"on", "enable", "enabled", "aktif", "aktifkan", "nyalakan"}
+	assistantOffWords   = []string{"off", "disable", "disabled", "nonaktif", "nonaktifkan", "matikan"}
+	assistantQueryWords = []string{"what", "which", "status", "current", "apa", "berapa"}
+)
+
+// ClaudeHandler menangani permintaan ke Claude Desktop. Perintah dipahami berdasarkan kata kunci
+// di registry preferensi, sehingga preferensi baru langsung bisa diatur lewat asisten.
 func ClaudeHandler(w http.ResponseWriter, r *http.Request) {
 	// Dapatkan ID pengguna dari konteks
 	userID := r.Context().Value("userID").(uint)
@@ -35,105 +45,139 @@ func ClaudeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Ambil preferensi pengguna saat ini
-	var preferences models.UserPreferences
-	result := config.DB.Where("user_id = ?", userID).First(&preferences)
-	if result.Error != nil {
-		http.Error(w, "Failed to get preferences: "+result.Error.Error(), http.StatusInternalServerError)
+	preferences, err := services.GetPreferences(userID)
+	if err != nil {
+		writePreferenceError(w, err)
 		return
 	}
 
-	// Logika sederhana untuk memproses perintah bahasa natural
-	message := strings.ToLower(req.Message)
-	action := ""
-	preferenceUpdated := false
-
-	// Proses perintah untuk mengubah tema
-	if strings.Contains(message, "theme") || strings.Contains(message, "tema") {
-		if strings.Contains(message, "dark") || strings.Contains(message, "gelap") {
-			preferences.Theme = "dark"
-			preferenceUpdated = true
-			action = "theme_updated"
-		} else if strings.Contains(message, "light") || strings.Contains(message, "terang") {
-			preferences.Theme = "light"
-			preferenceUpdated = true
-			action = "theme_updated"
+	// Cocokkan kata di pesan dengan kata kunci setiap preferensi
+	words := messageWords(req.Message)
+	changes := map[string]interface{}{}
+	var mentioned []models.PreferenceDefinition
+	for _, def := range models.PreferenceDefinitions() {
+		if !containsAnyWord(words, def.Keywords) {
+			continue
 		}
-	}
-
-	// Proses perintah untuk mengubah bahasa
-	if strings.Contains(message, "language") || strings.Contains(message, "bahasa") {
-		if strings.Contains(message, "english") || strings.Contains(message, "inggris") {
-			preferences.Language = "english"
-			preferenceUpdated = true
-			action = "language_updated"
-		} else if strings.Contains(message, "indonesia") || strings.Contains(message, "indonesian") {
-			preferences.Language = "indonesia"
-			preferenceUpdated = true
-			action = "language_updated"
-		} else if strings.Contains(message, "spanish") || strings.Contains(message, "spanyol") {
-			preferences.Language = "spanish"
-			preferenceUpdated = true
-			action = "language_updated"
-		}
-	}
-
-	// Proses perintah untuk mengubah notifikasi
-	if strings.Contains(message, "notification") || strings.Contains(message, "notifikasi") {
-		if strings.Contains(message, "on") || strings.Contains(message, "enable") || strings.Contains(message, "aktif") {
-			preferences.Notifications = true
-			preferenceUpdated = true
-			action = "notifications_updated"
-		} else if strings.Contains(message, "off") || strings.Contains(message, "disable") || strings.Contains(message, "nonaktif") {
-			preferences.Notifications = false
-			preferenceUpdated = true
-			action = "notifications_updated"
+		mentioned = append(mentioned, def)
+		if value, ok := requestedValue(def, words); ok {
+			changes[def.Key] = value
 		}
 	}
 
 	// Simpan perubahan jika ada yang diperbarui
-	if preferenceUpdated {
-		result = config.DB.Save(&preferences)
-		if result.Error != nil {
-			http.Error(w, "Failed to update preferences: "+result.Error.Error(), http.StatusInternalServerError)
+	if len(changes) > 0 {
+		preferences, err = services.ApplyPreferenceChanges(userID, changes, services.PreferenceChangeContext{
+			Source:  models.PreferenceSourceAssistant,
+			ActorID: userID,
+		})
+		if err != nil {
+			writePreferenceError(w, err)
 			return
 		}
 	}
 
 	// Buat pesan response yang sesuai
-	responseMsg := ""
-	if action == "theme_updated" {
-		responseMsg = "I've updated your theme to " + preferences.Theme + " mode."
-	} else if action == "language_updated" {
-		responseMsg = "I've changed your language preference to " + preferences.Language + "."
-	} else if action == "notifications_updated" {
-		if preferences.Notifications {
-			responseMsg = "I've turned notifications on for you."
-		} else {
-			responseMsg = "I've turned notifications off for you."
+	var messages []string
+	action := ""
+	for _, def := range mentioned {
+		if _, changed := changes[def.Key]; changed {
+			messages = append(messages, describeUpdate(def, preferences.Get(def.Key)))
+			action = def.Key + "_updated"
 		}
-	} else if strings.Contains(message, "what") && (strings.Contains(message, "theme") || strings.Contains(message, "tema")) {
-		responseMsg = "Your current theme is set to " + preferences.Theme + " mode."
-		action = "theme_info"
-	} else if strings.Contains(message, "what") && (strings.Contains(message, "language") || strings.Contains(message, "bahasa")) {
-		responseMsg = "Your current language is set to " + preferences.Language + "."
-		action = "language_info"
-	} else if strings.Contains(message, "notification") || strings.Contains(message, "notifikasi") && (strings.Contains(message, "status") || strings.Contains(message, "what")) {
-		if preferences.Notifications {
-			responseMsg = "Your notifications are currently enabled."
-		} else {
-			responseMsg = "Your notifications are currently disabled."
+	}
+	if len(messages) == 0 && containsAnyWord(words, assistantQueryWords) {
+		for _, def := range mentioned {
+			messages = append(messages, describeValue(def, preferences.Get(def.Key)))
+			action = def.Key + "_info"
 		}
-		action = "notifications_info"
-	} else {
-		responseMsg = "I'm sorry, I don't understand that command. You can ask me to change your theme, language, or notification settings."
+	}
+	if len(messages) == 0 {
+		messages = []string{"I'm sorry, I don't understand that command. You can ask me to change your " + preferenceLabels() + " settings."}
 		action = "unknown_command"
 	}
 
 	// Kirim respons
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(ClaudeResponse{
-		Message:     responseMsg,
-		Preferences: &preferences,
+		Message:     strings.Join(messages, " "),
+		Preferences: preferences,
 		Action:      action,
 	})
 }
+
+// requestedValue mencari nilai baru untuk preferensi di pesan: nilai enum lewat kata kuncinya,
+// boolean lewat kata seperti "on"/"off". Tipe lain tidak bisa diatur lewat asisten.
+func requestedValue(def models.PreferenceDefinition, words []string) (interface{}, bool) {
+	switch def.Type {
+	case models.PreferenceTypeEnum:
+		for _, value := range def.AllowedValues {
+			if containsAnyWord(words, append([]string{value}, def.ValueKeywords[value]...)) {
+				return value, true
+			}
+		}
+	case models.PreferenceTypeBoolean:
+		// "off" dicek lebih dulu: "nonaktifkan" tidak boleh dianggap "aktifkan"
+		if containsAnyWord(words, assistantOffWords) {
+			return false, true
+		}
+		if containsAnyWord(words, assistantOnWords) {
+			return true, true
+		}
+	}
+	return nil, false
+}
+
+// describeUpdate membuat kalimat konfirmasi perubahan preferensi
+func describeUpdate(def models.PreferenceDefinition, value interface{}) string {
+	if enabled, ok := value.(bool); ok {
+		if enabled {
+			return "I've turned " + def.Label + " on for you."
+		}
+		return "I've turned " + def.Label + " off for you."
+	}
+	return fmt.Sprintf("I've updated your %s to %v.", def.Label, value)
+}
+
+// describeValue membuat kalimat yang menjelaskan nilai preferensi saat ini
+func describeValue(def models.PreferenceDefinition, value interface{}) string {
+	if enabled, ok := value.(bool); ok {
+		if enabled {
+			return "Your " + def.Label + " setting is currently enabled."
+		}
+		return "Your " + def.Label + " setting is currently disabled."
+	}
+	return fmt.Sprintf("Your current %s is set to %v.", def.Label, value)
+}
+
+// preferenceLabels menggabungkan label semua preferensi, mis. "language, notifications, or theme"
+func preferenceLabels() string {
+	defs := models.PreferenceDefinitions()
+	labels := make([]string, 0, len(defs))
+	for _, def := range defs {
+		labels = append(labels, def.Label)
+	}
+	if len(labels) <= 1 {
+		return strings.Join(labels, "")
+	}
+	return strings.Join(labels[:len(labels)-1], ", ") + ", or " + labels[len(labels)-1]
+}
+
+// messageWords memecah pesan menjadi kata huruf kecil sehingga "on" tidak cocok dengan "notification"
+func messageWords(message string) []string {
+	return strings.FieldsFunc(strings.ToLower(message), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	})
+}
+
+// containsAnyWord memeriksa apakah salah satu kandidat muncul sebagai kata utuh
+func containsAnyWord(words []string, candidates []string) bool {
+	for _, word := range words {
+		for _, candidate := range candidates {
+			if word == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
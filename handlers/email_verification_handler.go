@@ -0,0 +1,97 @@
+// handlers/email_verification_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"main/config"
+	"main/models"
+	"main/services"
+)
+
+// VerifyEmailRequest merupakan struktur untuk permintaan verifikasi email
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// VerifyEmailHandler memverifikasi email pengguna menggunakan token dari tautan verifikasi
+func VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var req VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := services.VerifyEmail(req.Token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidVerificationToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, services.ErrEmailTaken) {
+			http.Error(w, "Username or email already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// ResendVerificationEmailHandler mengirim ulang tautan verifikasi ke email pengguna yang sedang login
+func ResendVerificationEmailHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	var user models.User
+	result := config.DB.First(&user, userID)
+	if result.Error != nil {
+		http.Error(w, "Failed to get user: "+result.Error.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if user.EmailVerified {
+		http.Error(w, "Email is already verified", http.StatusConflict)
+		return
+	}
+
+	// Setiap pengiriman ulang memperpanjang backoff agar endpoint tidak dipakai untuk membanjiri inbox
+	limiter := services.DefaultLoginLimiter()
+	key := services.VerifyEmailKey(user.ID)
+	if wait, err := limiter.Attempt(key); err != nil {
+		http.Error(w, "Failed to check resend limit: "+err.Error(), http.StatusInternalServerError)
+		return
+	} else if wait > 0 {
+		writeTooManyRequests(w, wait)
+		return
+	}
+
+	if err := services.SendVerificationEmail(user); err != nil {
+		if err := limiter.Release(key); err != nil {
+			log.Printf("Failed to release verification email resend for user %d: %v", user.ID, err)
+		}
+		http.Error(w, "Failed to send verification email", http.StatusInternalServerError)
+		return
+	}
+	if _, err := limiter.Failed(key); err != nil {
+		log.Printf("Failed to record verification email resend for user %d: %v", user.ID, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(MessageResponse{
+		Message: "A new verification link has been sent to " + user.Email + ".",
+	})
+}
@@ -0,0 +1,133 @@
+// handlers/export_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"main/models"
+	"main/services"
+
+	"github.com/gorilla/mux"
+)
+
+// ExportHandler mengekspor semua data pribadi pengguna sebagai bundle JSON. Akun kecil langsung
+// menerima file-nya; akun besar (atau request dengan ?async=true) mendapat 202 dan status ekspor background.
+func ExportHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	background := r.URL.Query().Get("async") == "true"
+	if !background {
+		var err error
+		background, err = services.DataExportNeedsBackground(userID)
+		if err != nil {
+			http.Error(w, "Failed to export data: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if background {
+		job, err := services.StartDataExport(userID)
+		if err != nil {
+			http.Error(w, "Failed to start export: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Kirim respons
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/api/user/export/"+job.ID)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(job)
+		return
+	}
+
+	bundle, err := services.BuildDataExport(userID)
+	if err != nil {
+		http.Error(w, "Failed to export data: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Kirim respons sebagai file unduhan
+	setExportHeaders(w, userID, bundle.ExportedAt)
+	json.NewEncoder(w).Encode(bundle)
+}
+
+// GetExportStatusHandler menampilkan status ekspor background. Status pertama setelah ekspor selesai
+// berisi download_url; tautan baru bisa dibuat lewat CreateExportLinkHandler.
+func GetExportStatusHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+	exportID := mux.Vars(r)["id"]
+
+	job, err := services.GetDataExport(userID, exportID)
+	if err == nil && job.Status == models.DataExportReady {
+		job, err = services.ClaimDataExportLink(userID, exportID)
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrDataExportNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get export: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// CreateExportLinkHandler membuat tautan unduh baru untuk ekspor yang sudah selesai.
+// Tautan sebelumnya tidak berlaku lagi.
+func CreateExportLinkHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+	exportID := mux.Vars(r)["id"]
+
+	job, err := services.CreateDataExportLink(userID, exportID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrDataExportNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, services.ErrDataExportNotReady):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to create download link: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// DownloadExportHandler mengirim hasil ekspor background. Rute ini publik: tautan unduh sendiri yang menjadi
+// kredensialnya sehingga bisa dibuka langsung di browser.
+func DownloadExportHandler(w http.ResponseWriter, r *http.Request) {
+	export, err := services.DownloadDataExport(r.URL.Query().Get("token"))
+	if err != nil {
+		if errors.Is(err, services.ErrDataExportNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to download export: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Kirim respons sebagai file unduhan
+	setExportHeaders(w, export.UserID, export.CreatedAt)
+	w.Write(export.Data)
+}
+
+// setExportHeaders menandai respons sebagai file JSON yang diunduh dan tidak boleh di-cache
+func setExportHeaders(w http.ResponseWriter, userID uint, exportedAt time.Time) {
+	filename := fmt.Sprintf("user-%d-export-%s.json", userID, exportedAt.UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Cache-Control", "no-store")
+}
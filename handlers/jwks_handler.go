@@ -0,0 +1,23 @@
+// handlers/jwks_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"main/utils"
+)
+
+// JWKSHandler mempublikasikan kunci publik JWT agar layanan lain bisa memverifikasi token secara mandiri
+func JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	keys, err := utils.LoadKeySet()
+	if err != nil {
+		http.Error(w, "Failed to load signing keys", http.StatusInternalServerError)
+		return
+	}
+
+	// Kirim respons (boleh di-cache sebentar oleh klien)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(keys.PublicJWKS())
+}
@@ -0,0 +1,76 @@
+// handlers/logout_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"main/services"
+	"main/utils"
+)
+
+// LogoutRequest merupakan struktur untuk permintaan logout (refresh token bersifat opsional)
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// LogoutHandler mencabut access token yang sedang dipakai dan family refresh token yang dikirim
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dan klaim token dari konteks
+	userID := r.Context().Value("userID").(uint)
+	claims := r.Context().Value("claims").(*utils.JWTClaim)
+
+	// Parse request body (boleh kosong)
+	var req LogoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Cabut access token saat ini
+	if err := services.Revocations.RevokeToken(claims.ID, userID, claims.ExpiresAt.Time); err != nil {
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+
+	// Akhiri sesi perangkat ini beserta semua refresh token-nya
+	if claims.SessionID != "" {
+		if err := services.RevokeRefreshFamily(claims.SessionID); err != nil {
+			http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Cabut refresh token jika dikirim
+	if req.RefreshToken != "" {
+		err := services.RevokeRefreshTokenForUser(req.RefreshToken, userID)
+		if err != nil && !errors.Is(err, services.ErrInvalidRefreshToken) {
+			http.Error(w, "Failed to revoke refresh token", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAllHandler mencabut semua access token dan refresh token milik pengguna di semua perangkat
+func LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dan klaim token dari konteks
+	userID := r.Context().Value("userID").(uint)
+	claims := r.Context().Value("claims").(*utils.JWTClaim)
+
+	if err := services.RevokeAllUserTokens(userID); err != nil {
+		http.Error(w, "Failed to revoke tokens", http.StatusInternalServerError)
+		return
+	}
+
+	// Token saat ini juga dicabut secara eksplisit karena batas pencabutan massal dibulatkan ke detik
+	if err := services.Revocations.RevokeToken(claims.ID, userID, claims.ExpiresAt.Time); err != nil {
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,92 @@
+// handlers/magic_link_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"main/services"
+)
+
+// MagicLinkRequest merupakan struktur untuk meminta tautan login lewat email
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+// ConsumeMagicLinkRequest merupakan struktur untuk menukar tautan login
+type ConsumeMagicLinkRequest struct {
+	Token string `json:"token"`
+}
+
+// MagicLinkHandler mengirim tautan login sekali pakai ke email pengguna
+func MagicLinkHandler(w http.ResponseWriter, r *http.Request) {
+	if !services.MagicLinkEnabled() {
+		http.Error(w, "Magic link login is disabled", http.StatusNotFound)
+		return
+	}
+
+	// Parse request body
+	var req MagicLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Email == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	// Respons selalu sama, baik email terdaftar maupun tidak
+	if err := services.RequestMagicLink(req.Email); err != nil {
+		log.Printf("Failed to create magic link: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(MessageResponse{
+		Message: "If the email can be used to sign in, a sign-in link has been sent.",
+	})
+}
+
+// ConsumeMagicLinkHandler menukar tautan login dengan respons yang sama seperti LoginHandler.
+// Token dibaca dari query (?token=) untuk GET atau dari body JSON untuk POST.
+func ConsumeMagicLinkHandler(w http.ResponseWriter, r *http.Request) {
+	if !services.MagicLinkEnabled() {
+		http.Error(w, "Magic link login is disabled", http.StatusNotFound)
+		return
+	}
+
+	var req ConsumeMagicLinkRequest
+	if r.Method == http.MethodGet {
+		req.Token = r.URL.Query().Get("token")
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := services.ConsumeMagicLink(req.Token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMagicLink) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Failed to sign in: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Akun yang dinonaktifkan admin tidak bisa login
+	if user.Disabled {
+		http.Error(w, "Account is disabled", http.StatusForbidden)
+		return
+	}
+
+	completeLogin(w, r, *user)
+}
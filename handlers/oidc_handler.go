@@ -0,0 +1,244 @@
+// handlers/oidc_handler.go
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"main/config"
+	"main/models"
+	"main/services"
+
+	"github.com/gorilla/mux"
+)
+
+// oidcStateCookie menyimpan state alur OIDC di browser yang memulainya, sehingga callback dengan state
+// milik orang lain (login CSRF) ditolak
+const oidcStateCookie = "oidc_state"
+
+// OIDCCallbackRequest merupakan struktur untuk callback OIDC (state dan code dari penyedia)
+type OIDCCallbackRequest struct {
+	State            string `json:"state"`
+	Code             string `json:"code"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// ListOIDCProvidersResponse merupakan struktur untuk daftar penyedia OIDC yang tersedia
+type ListOIDCProvidersResponse struct {
+	Providers []services.OIDCProvider `json:"providers"`
+}
+
+// ListIdentitiesResponse merupakan struktur untuk daftar identitas eksternal yang tertaut
+type ListIdentitiesResponse struct {
+	Identities []models.ExternalIdentity `json:"identities"`
+}
+
+// ListOIDCProvidersHandler menampilkan penyedia OIDC yang bisa dipakai untuk login
+func ListOIDCProvidersHandler(w http.ResponseWriter, r *http.Request) {
+	providers := services.OIDCProviders()
+	if providers == nil {
+		providers = []services.OIDCProvider{}
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ListOIDCProvidersResponse{
+		Providers: providers,
+	})
+}
+
+// OIDCAuthorizeHandler memulai login OIDC dan mengembalikan URL otorisasi penyedia
+func OIDCAuthorizeHandler(w http.ResponseWriter, r *http.Request) {
+	start, err := services.StartOIDCAuth(mux.Vars(r)["provider"], nil)
+	if err != nil {
+		writeOIDCError(w, err)
+		return
+	}
+	setOIDCStateCookie(w, start)
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(start)
+}
+
+// OIDCCallbackHandler menyelesaikan alur OIDC. Menerima POST JSON dari frontend, atau GET jika
+// redirect URL penyedia langsung mengarah ke API. Untuk alur login, responsnya sama dengan login
+// password (termasuk challenge 2FA); untuk alur penautan, responsnya identitas yang ditautkan.
+func OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	var req OIDCCallbackRequest
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		req = OIDCCallbackRequest{
+			State:            query.Get("state"),
+			Code:             query.Get("code"),
+			Error:            query.Get("error"),
+			ErrorDescription: query.Get("error_description"),
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.State == "" {
+		http.Error(w, "State is required", http.StatusBadRequest)
+		return
+	}
+
+	// State harus berasal dari browser ini; cookie hanya berlaku untuk satu percobaan
+	cookie, err := r.Cookie(oidcStateCookie)
+	clearOIDCStateCookie(w)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(req.State)) != 1 {
+		http.Error(w, services.ErrInvalidOIDCState.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Penyedia menolak atau pengguna membatalkan login
+	if req.Error != "" {
+		if err := services.CancelOIDCAuth(req.State); err != nil {
+			log.Printf("Failed to discard OIDC state: %v", err)
+		}
+		http.Error(w, "Identity provider returned an error: "+req.Error+" "+req.ErrorDescription, http.StatusBadRequest)
+		return
+	}
+
+	if req.Code == "" {
+		http.Error(w, "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := services.CompleteOIDCAuth(req.State, req.Code)
+	if err != nil {
+		writeOIDCError(w, err)
+		return
+	}
+
+	if result.Linked {
+		// Kirim respons
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result.Identity)
+		return
+	}
+
+	// Akun yang dinonaktifkan admin tidak bisa login lewat penyedia mana pun
+	if result.User.Disabled {
+		http.Error(w, "Account is disabled", http.StatusForbidden)
+		return
+	}
+
+	// Email dari penyedia yang belum terverifikasi tetap perlu dikonfirmasi
+	if result.Created && !result.User.EmailVerified {
+		if err := services.SendVerificationEmail(result.User); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", result.User.ID, err)
+		}
+	}
+
+	completeLogin(w, r, result.User)
+}
+
+// ListIdentitiesHandler menampilkan identitas eksternal yang tertaut ke pengguna
+func ListIdentitiesHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	identities, err := services.ListIdentities(userID)
+	if err != nil {
+		http.Error(w, "Failed to get identities: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ListIdentitiesResponse{
+		Identities: identities,
+	})
+}
+
+// LinkIdentityHandler memulai alur OIDC untuk menautkan identitas eksternal ke akun yang sedang login
+func LinkIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	start, err := services.StartOIDCAuth(mux.Vars(r)["provider"], &userID)
+	if err != nil {
+		writeOIDCError(w, err)
+		return
+	}
+	setOIDCStateCookie(w, start)
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(start)
+}
+
+// UnlinkIdentityHandler melepas identitas eksternal dari akun pengguna
+func UnlinkIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	identityID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid identity ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.UnlinkIdentity(userID, uint(identityID)); err != nil {
+		writeOIDCError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setOIDCStateCookie menyimpan state di cookie HttpOnly yang dikirim kembali ke callback
+func setOIDCStateCookie(w http.ResponseWriter, start *services.OIDCAuthStart) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    start.State,
+		Path:     "/api/auth/oidc",
+		Expires:  start.ExpiresAt,
+		MaxAge:   int(time.Until(start.ExpiresAt).Seconds()),
+		HttpOnly: true,
+		Secure:   config.GetEnvBool("OIDC_COOKIE_SECURE", true),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// clearOIDCStateCookie menghapus cookie state setelah callback
+func clearOIDCStateCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    "",
+		Path:     "/api/auth/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   config.GetEnvBool("OIDC_COOKIE_SECURE", true),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// writeOIDCError memetakan error layanan OIDC ke status HTTP
+func writeOIDCError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownOIDCProvider), errors.Is(err, services.ErrIdentityNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidOIDCState), errors.Is(err, services.ErrOIDCEmailRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidIDToken):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, services.ErrOIDCRegistrationDisabled), errors.Is(err, services.ErrAccountPendingDeletion):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrOIDCEmailInUse), errors.Is(err, services.ErrIdentityAlreadyLinked), errors.Is(err, services.ErrLastLoginMethod):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrOIDCExchangeFailed):
+		log.Printf("OIDC provider error: %v", err)
+		http.Error(w, services.ErrOIDCExchangeFailed.Error(), http.StatusBadGateway)
+	default:
+		http.Error(w, "Failed to process sign-in: "+err.Error(), http.StatusInternalServerError)
+	}
+}
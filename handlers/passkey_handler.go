@@ -0,0 +1,229 @@
+// handlers/passkey_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"main/models"
+	"main/services"
+
+	"github.com/gorilla/mux"
+)
+
+// FinishPasskeyRegistrationRequest merupakan struktur untuk menyelesaikan registrasi passkey
+type FinishPasskeyRegistrationRequest struct {
+	Name       string                                 `json:"name"`
+	Credential services.PasskeyRegistrationCredential `json:"credential"`
+}
+
+// BeginPasskeyLoginRequest merupakan struktur untuk memulai login passkey (username opsional)
+type BeginPasskeyLoginRequest struct {
+	Username string `json:"username"`
+}
+
+// FinishPasskeyLoginRequest merupakan struktur untuk menyelesaikan login passkey
+type FinishPasskeyLoginRequest struct {
+	Credential services.PasskeyAssertionCredential `json:"credential"`
+}
+
+// RenamePasskeyRequest merupakan struktur untuk mengganti nama passkey
+type RenamePasskeyRequest struct {
+	Name string `json:"name"`
+}
+
+// ListPasskeysResponse merupakan struktur untuk daftar passkey
+type ListPasskeysResponse struct {
+	Passkeys []models.WebAuthnCredential `json:"passkeys"`
+}
+
+// BeginPasskeyRegistrationHandler mengembalikan opsi untuk navigator.credentials.create()
+func BeginPasskeyRegistrationHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	options, err := services.BeginPasskeyRegistration(userID)
+	if err != nil {
+		http.Error(w, "Failed to start passkey registration: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(options)
+}
+
+// FinishPasskeyRegistrationHandler memverifikasi hasil navigator.credentials.create() dan menyimpan passkey
+func FinishPasskeyRegistrationHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	// Parse request body
+	var req FinishPasskeyRegistrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	passkey, err := services.FinishPasskeyRegistration(userID, req.Name, req.Credential)
+	if err != nil {
+		writePasskeyError(w, err)
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(passkey)
+}
+
+// BeginPasskeyLoginHandler mengembalikan opsi untuk navigator.credentials.get()
+func BeginPasskeyLoginHandler(w http.ResponseWriter, r *http.Request) {
+	// Body boleh kosong untuk login dengan passkey discoverable
+	var req BeginPasskeyLoginRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	options, err := services.BeginPasskeyLogin(strings.TrimSpace(req.Username))
+	if err != nil {
+		http.Error(w, "Failed to start passkey login: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(options)
+}
+
+// FinishPasskeyLoginHandler memverifikasi hasil navigator.credentials.get() dan menerbitkan sesi.
+// Passkey dengan verifikasi pengguna (PIN/biometrik) sudah multi-faktor sehingga tidak perlu kode 2FA;
+// tanpa verifikasi pengguna, akun dengan 2FA tetap mendapat challenge 2FA seperti login password.
+func FinishPasskeyLoginHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var req FinishPasskeyLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := services.FinishPasskeyLogin(req.Credential)
+	if err != nil {
+		writePasskeyError(w, err)
+		return
+	}
+
+	// Akun yang dinonaktifkan admin tidak bisa login
+	if result.User.Disabled {
+		http.Error(w, "Account is disabled", http.StatusForbidden)
+		return
+	}
+
+	if result.UserVerified {
+		writeSession(w, r, result.User)
+		return
+	}
+	completeLogin(w, r, result.User)
+}
+
+// ListPasskeysHandler menampilkan semua passkey milik pengguna
+func ListPasskeysHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	passkeys, err := services.ListPasskeys(userID)
+	if err != nil {
+		http.Error(w, "Failed to get passkeys: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ListPasskeysResponse{
+		Passkeys: passkeys,
+	})
+}
+
+// RenamePasskeyHandler mengganti nama passkey milik pengguna
+func RenamePasskeyHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	passkeyID, ok := parsePasskeyID(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req RenamePasskeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		http.Error(w, "Name is required (max 100 characters)", http.StatusBadRequest)
+		return
+	}
+
+	passkey, err := services.RenamePasskey(userID, passkeyID, name)
+	if err != nil {
+		writePasskeyError(w, err)
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(passkey)
+}
+
+// DeletePasskeyHandler menghapus passkey milik pengguna
+func DeletePasskeyHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	passkeyID, ok := parsePasskeyID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := services.DeletePasskey(userID, passkeyID); err != nil {
+		writePasskeyError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parsePasskeyID membaca {id} dari path; mengirim 400 dan mengembalikan false jika tidak valid
+func parsePasskeyID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid passkey ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writePasskeyError memetakan error layanan passkey ke status HTTP
+func writePasskeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrPasskeyNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidPasskeyChallenge):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidPasskey), errors.Is(err, services.ErrPasskeyCloneDetected):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, services.ErrPasskeyExists), errors.Is(err, services.ErrLastLoginMethod):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Failed to process passkey: "+err.Error(), http.StatusInternalServerError)
+	}
+}
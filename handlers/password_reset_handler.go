@@ -0,0 +1,82 @@
+// handlers/password_reset_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"main/services"
+)
+
+// ForgotPasswordRequest merupakan struktur untuk permintaan lupa password
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest merupakan struktur untuk permintaan reset password
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// MessageResponse merupakan struktur untuk respons berupa pesan sederhana
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// ForgotPasswordHandler mengirim tautan reset password ke email pengguna
+func ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Email == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	// Respons selalu sama, baik email terdaftar maupun tidak
+	services.RequestPasswordReset(req.Email)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(MessageResponse{
+		Message: "If an account with that email exists, a password reset link has been sent.",
+	})
+}
+
+// ResetPasswordHandler mengganti password menggunakan token reset
+func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Token == "" || req.Password == "" {
+		http.Error(w, "Token and password are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.ResetPassword(req.Token, req.Password); err != nil {
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if writeValidationError(w, err) {
+			return
+		}
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MessageResponse{
+		Message: "Your password has been reset. Please log in with your new password.",
+	})
+}
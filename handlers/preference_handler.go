@@ -3,99 +3,287 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"mime"
 	"net/http"
+	"strconv"
 
 	"main/config"
 	"main/models"
+	"main/services"
 )
 
-// UpdatePreferenceRequest merupakan struktur untuk memperbarui preferensi
-
-type UpdatePreferenceRequest struct {
-	Theme         *string `json:"theme,omitempty"`
-	Language      *string `json:"language,omitempty"`
-	Notifications *bool   `json:"notifications,omitempty"`
-}
+// UpdatePreferenceRequest berisi preferensi yang ingin diubah. Key-nya mengikuti registry preferensi
+// (lihat GET /api/preferences/schema), misalnya {"theme": "dark", "notifications": false}.
+type UpdatePreferenceRequest map[string]interface{}
 
 // GetPreferenceResponse merupakan struktur untuk respons preferensi
 type GetPreferenceResponse struct {
 	Preferences models.UserPreferences `json:"preferences"`
 }
 
+// RestorePreferencesRequest merupakan struktur untuk memulihkan preferensi ke versi sebelumnya
+type RestorePreferencesRequest struct {
+	Version *int64 `json:"version"`
+}
+
+// PreferenceSchemaResponse merupakan struktur untuk daftar definisi preferensi
+type PreferenceSchemaResponse struct {
+	Preferences []models.PreferenceDefinition `json:"preferences"`
+}
+
 // GetPreferencesHandler menangani permintaan untuk mengambil preferensi pengguna
 func GetPreferencesHandler(w http.ResponseWriter, r *http.Request) {
 	// Dapatkan ID pengguna dari konteks (yang diset oleh AuthMiddleware)
 	userID := r.Context().Value("userID").(uint)
 
 	// Ambil preferensi dari database
-	var preferences models.UserPreferences
-	result := config.DB.Where("user_id = ?", userID).First(&preferences)
-	if result.Error != nil {
-		http.Error(w, "Failed to get preferences: "+result.Error.Error(), http.StatusInternalServerError)
+	preferences, err := services.GetPreferences(userID)
+	if err != nil {
+		writePreferenceError(w, err)
+		return
+	}
+
+	// Klien yang sudah memegang versi terbaru cukup menerima 304 tanpa body
+	etag := services.PreferenceETag(preferences)
+	if match := r.Header.Get("If-None-Match"); match != "" && services.MatchETag(match, etag, true) {
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
 	// Kirim respons
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(GetPreferenceResponse{
-		Preferences: preferences,
-	})
+	writePreferences(w, preferences)
 }
 
-// UpdatePreferencesHandler menangani permintaan untuk memperbarui preferensi pengguna
+// Media type yang diterima PATCH /api/preferences
+const (
+	mediaTypeMergePatch = "application/merge-patch+json"
+	mediaTypeJSONPatch  = "application/json-patch+json"
+)
+
+// UpdatePreferencesHandler menangani POST /api/preferences dengan semantik lamanya: hanya key yang dikirim
+// yang berubah dan key bernilai null dibiarkan tidak berubah. Untuk mengembalikan key ke default, pakai PATCH.
 func UpdatePreferencesHandler(w http.ResponseWriter, r *http.Request) {
 	// Dapatkan ID pengguna dari konteks
 	userID := r.Context().Value("userID").(uint)
 
 	// Parse request body
 	var req UpdatePreferenceRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req == nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	// Ambil preferensi pengguna saat ini
-	var preferences models.UserPreferences
-	result := config.DB.Where("user_id = ?", userID).First(&preferences)
-	if result.Error != nil {
-		http.Error(w, "Failed to get preferences: "+result.Error.Error(), http.StatusInternalServerError)
+	// Null berarti "tidak diubah", bukan "kembalikan ke default" seperti pada merge patch
+	changes := UpdatePreferenceRequest{}
+	for key, value := range req {
+		if value != nil {
+			changes[key] = value
+		}
+	}
+
+	// Validasi dan simpan perubahan
+	preferences, err := services.ApplyPreferenceChanges(userID, changes, restPreferenceChange(r, userID))
+	if err != nil {
+		writePreferenceError(w, err)
 		return
 	}
 
-	// Update preferensi jika disediakan dalam request
-	updated := false
+	// Kirim respons
+	writePreferences(w, preferences)
+}
+
+// PatchPreferencesHandler menangani PATCH /api/preferences dengan JSON Merge Patch (RFC 7396)
+// atau JSON Patch (RFC 6902), sesuai Content-Type
+func PatchPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
 
-	if req.Theme != nil {
-		preferences.Theme = *req.Theme
-		updated = true
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case mediaTypeMergePatch:
+		mergePatchPreferences(w, r, userID)
+	case mediaTypeJSONPatch:
+		// Parse request body
+		var operations []services.JSONPatchOperation
+		if err := json.NewDecoder(r.Body).Decode(&operations); err != nil || operations == nil {
+			http.Error(w, "Invalid request body: expected a JSON Patch array", http.StatusBadRequest)
+			return
+		}
+
+		// Terapkan semua operasi secara atomik
+		preferences, err := services.JSONPatchPreferences(userID, operations, restPreferenceChange(r, userID))
+		if err != nil {
+			writePreferenceError(w, err)
+			return
+		}
+
+		// Kirim respons
+		writePreferences(w, preferences)
+	default:
+		w.Header().Set("Accept-Patch", mediaTypeMergePatch+", "+mediaTypeJSONPatch)
+		http.Error(w, "Unsupported patch format", http.StatusUnsupportedMediaType)
+	}
+}
+
+// ReplacePreferencesHandler menangani PUT /api/preferences: seluruh preferensi diganti dengan body,
+// key yang tidak dikirim dikembalikan ke default
+func ReplacePreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	// Parse request body
+	var req UpdatePreferenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
 	}
 
-	if req.Language != nil {
-		preferences.Language = *req.Language
-		updated = true
+	// Validasi dan ganti seluruh preferensi
+	preferences, err := services.ReplacePreferences(userID, req, restPreferenceChange(r, userID))
+	if err != nil {
+		writePreferenceError(w, err)
+		return
 	}
 
-	if req.Notifications != nil {
-		preferences.Notifications = *req.Notifications
-		updated = true
+	// Kirim respons
+	writePreferences(w, preferences)
+}
+
+// mergePatchPreferences menerapkan body JSON Merge Patch ke preferensi pengguna
+func mergePatchPreferences(w http.ResponseWriter, r *http.Request, userID uint) {
+	// Parse request body
+	var req UpdatePreferenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
 	}
 
-	// Simpan perubahan jika ada yang diperbarui
-	if updated {
-		result = config.DB.Save(&preferences)
-		if result.Error != nil {
-			http.Error(w, "Failed to update preferences: "+result.Error.Error(), http.StatusInternalServerError)
-			return
-		}
+	// Validasi dan simpan perubahan
+	preferences, err := services.MergePatchPreferences(userID, req, restPreferenceChange(r, userID))
+	if err != nil {
+		writePreferenceError(w, err)
+		return
+	}
+
+	// Kirim respons
+	writePreferences(w, preferences)
+}
+
+// restPreferenceChange menyusun konteks perubahan untuk permintaan REST pengguna sendiri
+func restPreferenceChange(r *http.Request, userID uint) services.PreferenceChangeContext {
+	return services.PreferenceChangeContext{
+		Source:  models.PreferenceSourceREST,
+		ActorID: userID,
+		IfMatch: r.Header.Get("If-Match"),
 	}
+}
 
+// PreferenceSchemaHandler menampilkan definisi semua preferensi: key, tipe, nilai yang diizinkan, default dan deskripsi
+func PreferenceSchemaHandler(w http.ResponseWriter, r *http.Request) {
 	// Kirim respons
 	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PreferenceSchemaResponse{
+		Preferences: models.PreferenceDefinitions(),
+	})
+}
+
+// PreferenceHistoryHandler menampilkan riwayat perubahan preferensi pengguna, terbaru lebih dulu
+// (?key=, ?page=, ?page_size=)
+func PreferenceHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	writePreferenceHistory(w, r, userID)
+}
+
+// RestorePreferencesHandler mengembalikan preferensi pengguna ke keadaannya pada versi tertentu
+func RestorePreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	// Parse request body
+	var req RestorePreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Version == nil {
+		http.Error(w, "Version is required", http.StatusBadRequest)
+		return
+	}
+
+	// Pulihkan preferensi; pemulihan dicatat sebagai versi baru di riwayat
+	change := restPreferenceChange(r, userID)
+	change.Source = models.PreferenceSourceRestore
+	preferences, err := services.RestorePreferences(userID, *req.Version, change)
+	if err != nil {
+		writePreferenceError(w, err)
+		return
+	}
+
+	// Kirim respons
+	writePreferences(w, preferences)
+}
+
+// writePreferences mengirim preferensi beserta ETag-nya, yang bisa dipakai klien di If-Match
+// untuk perubahan berikutnya atau di If-None-Match saat polling
+func writePreferences(w http.ResponseWriter, preferences *models.UserPreferences) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", services.PreferenceETag(preferences))
+	w.Header().Set("Accept-Patch", mediaTypeMergePatch+", "+mediaTypeJSONPatch)
 	json.NewEncoder(w).Encode(GetPreferenceResponse{
-		Preferences: preferences,
+		Preferences: *preferences,
 	})
 }
 
+// writePreferenceHistory mengirim satu halaman riwayat preferensi milik userID
+func writePreferenceHistory(w http.ResponseWriter, r *http.Request, userID uint) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	pageSize, _ := strconv.Atoi(query.Get("page_size"))
+
+	history, err := services.ListPreferenceHistory(userID, query.Get("key"), page, pageSize)
+	if err != nil {
+		writePreferenceError(w, err)
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// writePreferenceError memetakan error layanan preferensi ke status HTTP
+func writePreferenceError(w http.ResponseWriter, err error) {
+	if writeValidationError(w, err) {
+		return
+	}
+	var patchErr *services.PreferencePatchError
+	if errors.As(err, &patchErr) {
+		http.Error(w, "Invalid patch: "+patchErr.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, services.ErrPreferencePatchTestFailed) {
+		http.Error(w, "Patch test failed: "+err.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, services.ErrPreferencePreconditionFailed) {
+		http.Error(w, "Preferences were modified by another request; reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	if errors.Is(err, services.ErrPreferenceVersionNotFound) {
+		http.Error(w, "Preference version not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, services.ErrPreferencesNotFound) {
+		http.Error(w, "Failed to get preferences: "+err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, "Failed to process preferences: "+err.Error(), http.StatusInternalServerError)
+}
+
 // GetUserHandler menangani permintaan untuk mengambil data pengguna dengan preferensi
 func GetUserHandler(w http.ResponseWriter, r *http.Request) {
 	// Dapatkan ID pengguna dari konteks
@@ -103,7 +291,7 @@ func GetUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Ambil data pengguna dengan preferensi
 	var user models.User
-	result := config.DB.Preload("Preferences").First(&user, userID)
+	result := config.DB.Preload("Preferences.Values").First(&user, userID)
 	if result.Error != nil {
 		http.Error(w, "Failed to get user: "+result.Error.Error(), http.StatusInternalServerError)
 		return
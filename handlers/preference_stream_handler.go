@@ -0,0 +1,173 @@
+// handlers/preference_stream_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"main/models"
+	"main/services"
+	"main/utils"
+)
+
+// preferenceStreamRetry adalah jeda sambung ulang (milidetik) yang disarankan ke EventSource
+const preferenceStreamRetry = 5000
+
+// PreferenceStreamHandler mengirim perubahan preferensi pengguna secara real-time melalui Server-Sent Events.
+// Setiap event berisi satu versi riwayat preferensi dengan id = versi, sehingga klien yang tersambung ulang
+// (header Last-Event-ID atau ?last_event_id=) menerima perubahan yang terlewat terlebih dahulu.
+func PreferenceStreamHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	lastVersion := int64(-1)
+	if lastEventID != "" {
+		version, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || version < 0 {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		lastVersion = version
+	}
+
+	// Berlangganan sebelum membaca versi saat ini agar tidak ada perubahan yang terlewat di antaranya
+	events, cancel := services.DefaultPreferenceBroker().Subscribe(userID)
+	defer cancel()
+
+	preferences, err := services.GetPreferences(userID)
+	if err != nil {
+		writePreferenceError(w, err)
+		return
+	}
+
+	// Tanpa Last-Event-ID, stream dimulai dari versi saat ini
+	var replay []services.PreferenceEvent
+	if lastVersion < 0 {
+		lastVersion = preferences.Version
+	} else if lastVersion < preferences.Version {
+		replay, err = services.PreferenceEventsSince(userID, lastVersion)
+		if err != nil {
+			http.Error(w, "Failed to load preference history: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // matikan buffering di reverse proxy (nginx)
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", preferenceStreamRetry)
+	for _, event := range replay {
+		if err := writePreferenceEvent(w, event); err != nil {
+			return
+		}
+		lastVersion = event.Version
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(services.PreferenceStreamHeartbeat())
+	defer heartbeat.Stop()
+
+	// Stream ditutup saat kredensialnya kedaluwarsa; klien tersambung ulang dengan token baru
+	expired := streamExpiry(r)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-expired:
+			return
+		case event, ok := <-events:
+			if !ok {
+				// Diputus broker karena terlalu lambat; klien melanjutkan dari Last-Event-ID
+				return
+			}
+			if event.Version <= lastVersion {
+				continue
+			}
+			// Ada versi yang terlewat (misalnya notifikasi hilang): susul dari riwayat
+			pending := []services.PreferenceEvent{event}
+			if event.Version > lastVersion+1 {
+				pending, err = services.PreferenceEventsSince(userID, lastVersion)
+				if err != nil {
+					return
+				}
+			}
+			for _, event := range pending {
+				if event.Version <= lastVersion {
+					continue
+				}
+				if err := writePreferenceEvent(w, event); err != nil {
+					return
+				}
+				lastVersion = event.Version
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if streamRevoked(r) {
+				return
+			}
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writePreferenceEvent menulis satu event SSE "preferences"
+func writePreferenceEvent(w http.ResponseWriter, event services.PreferenceEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: preferences\ndata: %s\n\n", event.Version, data)
+	return err
+}
+
+// streamExpiry mengembalikan channel yang terisi saat access token atau API token kedaluwarsa
+// (nil jika token tidak punya masa berlaku)
+func streamExpiry(r *http.Request) <-chan time.Time {
+	var expiresAt *time.Time
+	if claims, ok := r.Context().Value("claims").(*utils.JWTClaim); ok && claims.ExpiresAt != nil {
+		expiresAt = &claims.ExpiresAt.Time
+	}
+	if token, ok := r.Context().Value("apiToken").(*models.APIToken); ok && token.ExpiresAt != nil {
+		expiresAt = token.ExpiresAt
+	}
+	if expiresAt == nil {
+		return nil
+	}
+	return time.After(time.Until(*expiresAt))
+}
+
+// streamRevoked memeriksa ulang apakah kredensial stream sudah dicabut: access token (logout atau sesi
+// dicabut) atau API token (dihapus)
+func streamRevoked(r *http.Request) bool {
+	if token, ok := r.Context().Value("apiToken").(*models.APIToken); ok {
+		revoked, err := services.APITokenRevoked(token)
+		return err == nil && revoked
+	}
+
+	claims, ok := r.Context().Value("claims").(*utils.JWTClaim)
+	if !ok {
+		return false
+	}
+	revoked, err := services.Revocations.IsRevoked(claims)
+	return err == nil && revoked
+}
@@ -0,0 +1,59 @@
+// handlers/refresh_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"main/config"
+	"main/models"
+	"main/services"
+)
+
+// RefreshRequest merupakan struktur untuk permintaan refresh token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshHandler menukar refresh token dengan access token dan refresh token baru (rotasi)
+func RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.RefreshToken == "" {
+		http.Error(w, "Refresh token is required", http.StatusBadRequest)
+		return
+	}
+
+	// Rotasi refresh token
+	pair, err := services.RotateRefreshToken(req.RefreshToken, sessionMetadata(r))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, services.ErrAccountDisabled) {
+			http.Error(w, "Account is disabled", http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	// Ambil data pengguna dengan preferensi untuk response
+	var user models.User
+	result := config.DB.Preload("Preferences.Values").First(&user, pair.UserID)
+	if result.Error != nil {
+		http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	// Kirim response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newAuthResponse(pair, user))
+}
@@ -0,0 +1,86 @@
+// handlers/session_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"main/models"
+	"main/services"
+	"main/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// SessionResponse adalah sesi beserta penanda apakah sesi itu yang sedang dipakai request ini
+type SessionResponse struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+// ListSessionsResponse merupakan struktur untuk daftar sesi aktif
+type ListSessionsResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+}
+
+// ListSessionsHandler menampilkan semua perangkat tempat pengguna sedang login
+func ListSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	sessions, err := services.ListSessions(userID)
+	if err != nil {
+		http.Error(w, "Failed to get sessions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	currentID := currentSessionID(r)
+	response := ListSessionsResponse{Sessions: []SessionResponse{}}
+	for _, session := range sessions {
+		response.Sessions = append(response.Sessions, SessionResponse{
+			Session: session,
+			Current: session.ID == currentID,
+		})
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RevokeSessionHandler mengakhiri sesi di satu perangkat
+func RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	if err := services.RevokeSession(userID, mux.Vars(r)["id"]); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to revoke session: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// currentSessionID mengembalikan ID sesi dari access token request ini (kosong untuk API token)
+func currentSessionID(r *http.Request) string {
+	claims, ok := r.Context().Value("claims").(*utils.JWTClaim)
+	if !ok {
+		return ""
+	}
+	return claims.SessionID
+}
+
+// sessionMetadata mengambil informasi perangkat dari request. Klien boleh mengirim nama perangkat
+// di header X-Device-Name; jika tidak, nama ditebak dari User-Agent.
+func sessionMetadata(r *http.Request) services.SessionMetadata {
+	return services.SessionMetadata{
+		DeviceName: r.Header.Get("X-Device-Name"),
+		UserAgent:  r.UserAgent(),
+		IP:         utils.ClientIP(r),
+	}
+}
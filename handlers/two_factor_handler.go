@@ -0,0 +1,183 @@
+// handlers/two_factor_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"main/config"
+	"main/models"
+	"main/services"
+)
+
+// MFAChallengeResponse dikirim oleh login jika pengguna mengaktifkan 2FA
+type MFAChallengeResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// TOTPSetupResponse berisi secret dan URI otpauth untuk didaftarkan ke aplikasi authenticator
+type TOTPSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+// TOTPVerifyRequest merupakan struktur untuk konfirmasi setup 2FA
+type TOTPVerifyRequest struct {
+	Code string `json:"code"`
+}
+
+// RecoveryCodesResponse berisi kode pemulihan yang hanya ditampilkan sekali
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TOTPDisableRequest merupakan struktur untuk menonaktifkan 2FA
+type TOTPDisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// MFAChallengeRequest merupakan struktur untuk langkah kedua login
+type MFAChallengeRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+// TOTPSetupHandler memulai pendaftaran authenticator TOTP untuk pengguna
+func TOTPSetupHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	secret, uri, err := services.SetupTOTP(userID)
+	if err != nil {
+		if errors.Is(err, services.ErrTOTPAlreadyEnabled) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to set up two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TOTPSetupResponse{
+		Secret:     secret,
+		OTPAuthURL: uri,
+	})
+}
+
+// TOTPVerifyHandler mengaktifkan 2FA setelah kode pertama dari authenticator terverifikasi
+func TOTPVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	// Parse request body
+	var req TOTPVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := services.VerifyTOTPSetup(userID, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTOTPAlreadyEnabled):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, services.ErrTOTPSetupRequired):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrInvalidSecondFactor):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		default:
+			http.Error(w, "Failed to enable two-factor authentication", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Kirim respons
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+}
+
+// TOTPDisableHandler menonaktifkan 2FA setelah password dan kode dikonfirmasi
+func TOTPDisableHandler(w http.ResponseWriter, r *http.Request) {
+	// Dapatkan ID pengguna dari konteks
+	userID := r.Context().Value("userID").(uint)
+
+	// Parse request body
+	var req TOTPDisableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Password == "" || req.Code == "" {
+		http.Error(w, "Password and code are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.DisableTOTP(userID, req.Password, req.Code); err != nil {
+		switch {
+		case errors.Is(err, services.ErrTOTPNotEnabled):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrInvalidSecondFactor):
+			http.Error(w, "Invalid password or authentication code", http.StatusUnauthorized)
+		default:
+			http.Error(w, "Failed to disable two-factor authentication", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MFAChallengeHandler menukar token "mfa pending" dan kode 2FA dengan token sesi penuh
+func MFAChallengeHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var req MFAChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.MFAToken == "" || req.Code == "" {
+		http.Error(w, "MFA token and code are required", http.StatusBadRequest)
+		return
+	}
+
+	pair, err := services.CompleteMFAChallenge(req.MFAToken, req.Code, sessionMetadata(r))
+	if err != nil {
+		var rateLimitErr *services.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			writeTooManyRequests(w, rateLimitErr.RetryAfter)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidMFAToken) || errors.Is(err, services.ErrInvalidSecondFactor) || errors.Is(err, services.ErrTOTPNotEnabled) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, services.ErrAccountDisabled) {
+			http.Error(w, "Account is disabled", http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Failed to complete two-factor challenge", http.StatusInternalServerError)
+		return
+	}
+
+	// Load pengguna dengan preferensi untuk response
+	var user models.User
+	result := config.DB.Preload("Preferences.Values").First(&user, pair.UserID)
+	if result.Error != nil {
+		http.Error(w, "Failed to get user: "+result.Error.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Kirim response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newAuthResponse(pair, user))
+}
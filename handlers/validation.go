@@ -0,0 +1,33 @@
+// handlers/validation.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"main/services"
+)
+
+// ValidationErrorResponse merupakan struktur respons 422 berisi daftar field yang tidak valid
+type ValidationErrorResponse struct {
+	Error  string                `json:"error"`
+	Fields []services.FieldError `json:"fields"`
+}
+
+// writeValidationError mengirim 422 dengan daftar field error jika err adalah *services.ValidationError.
+// Mengembalikan false (tanpa menulis respons) untuk error lain.
+func writeValidationError(w http.ResponseWriter, err error) bool {
+	var validationErr *services.ValidationError
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(ValidationErrorResponse{
+		Error:  "Validation failed",
+		Fields: validationErr.Fields,
+	})
+	return true
+}
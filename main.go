@@ -9,6 +9,9 @@ import (
 	"main/config"
 	"main/handlers"
 	"main/middleware"
+	"main/models"
+	"main/services"
+	"main/utils"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
@@ -18,30 +21,128 @@ func main() {
 	// Inisialisasi database
 	config.InitDatabase()
 
+	// Beri role admin ke pengguna di ADMIN_USERNAMES
+	services.BootstrapAdmins()
+
+	// Muat kunci penandatangan JWT
+	if _, err := utils.LoadKeySet(); err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
+
+	// Bersihkan daftar token yang dicabut secara berkala
+	services.Revocations.StartJanitor(time.Hour)
+
+	// Hapus catatan tautan login sekali pakai yang sudah kedaluwarsa
+	services.StartMagicLinkJanitor(time.Hour)
+
+	// Hapus ekspor data yang kedaluwarsa dan tandai ekspor yang terhenti sebagai gagal
+	services.StartDataExportJanitor(time.Hour)
+
+	// Hapus permanen akun yang masa tenggang penghapusannya sudah berakhir
+	services.StartAccountPurger(time.Hour)
+
 	// Inisialisasi router
 	router := mux.NewRouter()
 
 	// Rute publik (tidak perlu autentikasi)
+	router.HandleFunc("/.well-known/jwks.json", handlers.JWKSHandler).Methods("GET")
 	router.HandleFunc("/api/auth/register", handlers.RegisterHandler).Methods("POST")
 	router.HandleFunc("/api/auth/login", handlers.LoginHandler).Methods("POST")
+	router.HandleFunc("/api/auth/refresh", handlers.RefreshHandler).Methods("POST")
+	router.HandleFunc("/api/auth/password/forgot", handlers.ForgotPasswordHandler).Methods("POST")
+	router.HandleFunc("/api/auth/password/reset", handlers.ResetPasswordHandler).Methods("POST")
+	router.HandleFunc("/api/auth/verify-email", handlers.VerifyEmailHandler).Methods("POST")
+	router.HandleFunc("/api/auth/account/restore", handlers.RestoreAccountHandler).Methods("POST")
+	router.HandleFunc("/api/auth/2fa/challenge", handlers.MFAChallengeHandler).Methods("POST")
+	router.HandleFunc("/api/auth/magic-link", handlers.MagicLinkHandler).Methods("POST")
+	router.HandleFunc("/api/auth/magic-link/consume", handlers.ConsumeMagicLinkHandler).Methods("GET", "POST")
+	router.HandleFunc("/api/auth/passkeys/login/begin", handlers.BeginPasskeyLoginHandler).Methods("POST")
+	router.HandleFunc("/api/auth/passkeys/login/finish", handlers.FinishPasskeyLoginHandler).Methods("POST")
+	router.HandleFunc("/api/auth/oidc/providers", handlers.ListOIDCProvidersHandler).Methods("GET")
+	router.HandleFunc("/api/auth/oidc/{provider}/authorize", handlers.OIDCAuthorizeHandler).Methods("GET")
+	router.HandleFunc("/api/auth/oidc/callback", handlers.OIDCCallbackHandler).Methods("GET", "POST")
+	router.HandleFunc("/api/exports/download", handlers.DownloadExportHandler).Methods("GET")
+
+	// Stream perubahan preferensi (SSE). EventSource di browser tidak bisa mengirim header Authorization,
+	// jadi rute ini juga menerima token lewat ?access_token=
+	middleware.RequireScope(router.Handle("/api/preferences/stream",
+		middleware.AllowQueryToken(middleware.AuthMiddleware(http.HandlerFunc(handlers.PreferenceStreamHandler)))).Methods("GET"),
+		models.ScopePreferencesRead)
 
 	// Rute untuk manajemen preferensi (memerlukan autentikasi)
 	protectedRouter := router.PathPrefix("/api").Subrouter()
 	protectedRouter.Use(middleware.AuthMiddleware)
 
-	protectedRouter.HandleFunc("/preferences", handlers.GetPreferencesHandler).Methods("GET")
-	protectedRouter.HandleFunc("/preferences", handlers.UpdatePreferencesHandler).Methods("POST")
-	protectedRouter.HandleFunc("/user", handlers.GetUserHandler).Methods("GET")
+	protectedRouter.HandleFunc("/auth/logout", handlers.LogoutHandler).Methods("POST")
+	protectedRouter.HandleFunc("/auth/logout-all", handlers.LogoutAllHandler).Methods("POST")
+	protectedRouter.HandleFunc("/auth/verify-email/resend", handlers.ResendVerificationEmailHandler).Methods("POST")
+	protectedRouter.HandleFunc("/auth/2fa/setup", handlers.TOTPSetupHandler).Methods("POST")
+	protectedRouter.HandleFunc("/auth/2fa/verify", handlers.TOTPVerifyHandler).Methods("POST")
+	protectedRouter.HandleFunc("/auth/2fa/disable", handlers.TOTPDisableHandler).Methods("POST")
+
+	// Rute yang dibungkus RequireScope juga bisa diakses dengan API token yang memiliki scope tersebut
+	middleware.RequireScope(protectedRouter.HandleFunc("/preferences", handlers.GetPreferencesHandler).Methods("GET"), models.ScopePreferencesRead)
+	middleware.RequireScope(protectedRouter.HandleFunc("/preferences", handlers.PatchPreferencesHandler).Methods("PATCH"), models.ScopePreferencesWrite)
+	middleware.RequireScope(protectedRouter.HandleFunc("/preferences", handlers.ReplacePreferencesHandler).Methods("PUT"), models.ScopePreferencesWrite)
+	middleware.RequireScope(protectedRouter.HandleFunc("/preferences", handlers.UpdatePreferencesHandler).Methods("POST"), models.ScopePreferencesWrite)
+	middleware.RequireScope(protectedRouter.HandleFunc("/preferences/history", handlers.PreferenceHistoryHandler).Methods("GET"), models.ScopePreferencesRead)
+	middleware.RequireScope(protectedRouter.HandleFunc("/preferences/restore", handlers.RestorePreferencesHandler).Methods("POST"), models.ScopePreferencesWrite)
+	middleware.RequireScope(protectedRouter.HandleFunc("/preferences/schema", handlers.PreferenceSchemaHandler).Methods("GET"), models.ScopePreferencesRead)
+	middleware.RequireScope(protectedRouter.HandleFunc("/user", handlers.GetUserHandler).Methods("GET"), models.ScopeUserRead)
+	protectedRouter.HandleFunc("/user", handlers.DeleteAccountHandler).Methods("DELETE")
+	protectedRouter.HandleFunc("/user/export", handlers.ExportHandler).Methods("GET")
+	protectedRouter.HandleFunc("/user/export/{id}", handlers.GetExportStatusHandler).Methods("GET")
+	protectedRouter.HandleFunc("/user/export/{id}/link", handlers.CreateExportLinkHandler).Methods("POST")
+	protectedRouter.HandleFunc("/user/password", handlers.ChangePasswordHandler).Methods("PUT")
+	protectedRouter.HandleFunc("/user/email", handlers.ChangeEmailHandler).Methods("PUT")
+
+	// Rute untuk personal access token / API key
+	protectedRouter.HandleFunc("/user/tokens", handlers.ListAPITokensHandler).Methods("GET")
+	protectedRouter.HandleFunc("/user/tokens", handlers.CreateAPITokenHandler).Methods("POST")
+	protectedRouter.HandleFunc("/user/tokens/{id:[0-9]+}", handlers.GetAPITokenHandler).Methods("GET")
+	protectedRouter.HandleFunc("/user/tokens/{id:[0-9]+}", handlers.UpdateAPITokenHandler).Methods("PATCH")
+	protectedRouter.HandleFunc("/user/tokens/{id:[0-9]+}", handlers.DeleteAPITokenHandler).Methods("DELETE")
+
+	// Rute untuk identitas eksternal (OIDC) yang tertaut
+	protectedRouter.HandleFunc("/user/identities", handlers.ListIdentitiesHandler).Methods("GET")
+	protectedRouter.HandleFunc("/user/identities/{provider}/link", handlers.LinkIdentityHandler).Methods("POST")
+	protectedRouter.HandleFunc("/user/identities/{id:[0-9]+}", handlers.UnlinkIdentityHandler).Methods("DELETE")
+
+	// Rute untuk passkey (WebAuthn)
+	protectedRouter.HandleFunc("/user/passkeys", handlers.ListPasskeysHandler).Methods("GET")
+	protectedRouter.HandleFunc("/user/passkeys/register/begin", handlers.BeginPasskeyRegistrationHandler).Methods("POST")
+	protectedRouter.HandleFunc("/user/passkeys/register/finish", handlers.FinishPasskeyRegistrationHandler).Methods("POST")
+	protectedRouter.HandleFunc("/user/passkeys/{id:[0-9]+}", handlers.RenamePasskeyHandler).Methods("PATCH")
+	protectedRouter.HandleFunc("/user/passkeys/{id:[0-9]+}", handlers.DeletePasskeyHandler).Methods("DELETE")
+
+	// Rute untuk sesi login aktif per perangkat
+	protectedRouter.HandleFunc("/user/sessions", handlers.ListSessionsHandler).Methods("GET")
+	protectedRouter.HandleFunc("/user/sessions/{id}", handlers.RevokeSessionHandler).Methods("DELETE")
 
 	// Rute untuk Claude Desktop (memerlukan autentikasi)
-	protectedRouter.HandleFunc("/claude", handlers.ClaudeHandler).Methods("POST")
+	middleware.RequireScope(protectedRouter.HandleFunc("/claude", handlers.ClaudeHandler).Methods("POST"), models.ScopeAssistant)
+
+	// Rute admin (memerlukan autentikasi dan permission sesuai role)
+	adminRouter := router.PathPrefix("/api/admin").Subrouter()
+	adminRouter.Use(middleware.AuthMiddleware)
+
+	adminRouter.Handle("/users", middleware.RequirePermission(models.PermissionUsersRead)(http.HandlerFunc(handlers.AdminListUsersHandler))).Methods("GET")
+	adminRouter.Handle("/users/{id:[0-9]+}", middleware.RequirePermission(models.PermissionUsersRead)(http.HandlerFunc(handlers.AdminGetUserHandler))).Methods("GET")
+	adminRouter.Handle("/users/{id:[0-9]+}", middleware.RequirePermission(models.PermissionUsersDelete)(http.HandlerFunc(handlers.AdminDeleteUserHandler))).Methods("DELETE")
+	adminRouter.Handle("/users/{id:[0-9]+}/disable", middleware.RequirePermission(models.PermissionUsersWrite)(http.HandlerFunc(handlers.AdminDisableUserHandler))).Methods("POST")
+	adminRouter.Handle("/users/{id:[0-9]+}/enable", middleware.RequirePermission(models.PermissionUsersWrite)(http.HandlerFunc(handlers.AdminEnableUserHandler))).Methods("POST")
+	adminRouter.Handle("/users/{id:[0-9]+}/role", middleware.RequirePermission(models.PermissionUsersWrite)(http.HandlerFunc(handlers.AdminSetRoleHandler))).Methods("PUT")
+	adminRouter.Handle("/users/{id:[0-9]+}/preferences", middleware.RequirePermission(models.PermissionPreferencesManage)(http.HandlerFunc(handlers.AdminGetPreferencesHandler))).Methods("GET")
+	adminRouter.Handle("/users/{id:[0-9]+}/preferences", middleware.RequirePermission(models.PermissionPreferencesManage)(http.HandlerFunc(handlers.AdminUpdatePreferencesHandler))).Methods("PUT")
+	adminRouter.Handle("/users/{id:[0-9]+}/preferences/history", middleware.RequirePermission(models.PermissionPreferencesManage)(http.HandlerFunc(handlers.AdminPreferenceHistoryHandler))).Methods("GET")
+	adminRouter.Handle("/lockouts/unlock", middleware.RequirePermission(models.PermissionLockoutsManage)(http.HandlerFunc(handlers.UnlockLoginHandler))).Methods("POST")
 
 	// Konfigurasi CORS
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"}, // Sesuaikan untuk produksi
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Authorization", "Content-Type"},
-		ExposedHeaders:   []string{""},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "X-Device-Name", "If-Match", "If-None-Match", "Last-Event-ID"},
+		ExposedHeaders:   []string{"Retry-After", "ETag", "Accept-Patch"},
 		AllowCredentials: true,
 		MaxAge:           int(12 * time.Hour / time.Second),
 	})
@@ -3,13 +3,23 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
+	"main/services"
 	"main/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// Metode autentikasi yang disimpan di konteks request dengan kunci "authMethod"
+const (
+	AuthMethodJWT      = "jwt"
+	AuthMethodAPIToken = "api_token"
 )
 
-// AuthMiddleware adalah middleware untuk memeriksa JWT token
+// AuthMiddleware adalah middleware untuk memeriksa JWT token atau personal access token (API key)
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Dapatkan header Authorization
@@ -28,6 +38,12 @@ func AuthMiddleware(next http.Handler) http.Handler {
 
 		tokenString := parts[1]
 
+		// Personal access token (API key) ditangani terpisah dari JWT
+		if services.IsAPIToken(tokenString) {
+			authenticateAPIToken(w, r, next, tokenString)
+			return
+		}
+
 		// Validasi token
 		claims, err := utils.ValidateToken(tokenString)
 		if err != nil {
@@ -35,8 +51,83 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		// Tambahkan user ID ke konteks request
+		// Tolak token yang sudah dicabut (logout)
+		revoked, err := services.Revocations.IsRevoked(claims)
+		if err != nil {
+			http.Error(w, "Failed to validate token", http.StatusInternalServerError)
+			return
+		}
+		if revoked {
+			http.Error(w, "Token has been revoked", http.StatusUnauthorized)
+			return
+		}
+
+		// Catat aktivitas terakhir sesi perangkat ini
+		services.TouchSession(claims.SessionID, utils.ClientIP(r))
+
+		// Klaim di token bisa sudah usang, jadi status verifikasi terbaru dicek ke database sebelum menolak
+		if !claims.EmailVerified && !checkEmailVerification(w, r, claims.UserID) {
+			return
+		}
+
+		// Tambahkan user ID dan klaim token ke konteks request
 		ctx := context.WithValue(r.Context(), "userID", claims.UserID)
+		ctx = context.WithValue(ctx, "claims", claims)
+		ctx = context.WithValue(ctx, "role", claims.Role)
+		ctx = context.WithValue(ctx, "authMethod", AuthMethodJWT)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// authenticateAPIToken memvalidasi personal access token dan memastikan rute mengizinkan scope-nya
+func authenticateAPIToken(w http.ResponseWriter, r *http.Request, next http.Handler, tokenString string) {
+	token, err := services.AuthenticateAPIToken(tokenString)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAPIToken) {
+			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Failed to validate token", http.StatusInternalServerError)
+		return
+	}
+
+	// Rute tanpa scope terdaftar hanya bisa diakses dengan sesi login (default tolak)
+	scope, ok := routeScopes[mux.CurrentRoute(r)]
+	if !ok {
+		http.Error(w, "API tokens are not allowed for this endpoint", http.StatusForbidden)
+		return
+	}
+	if !token.HasScope(scope) {
+		http.Error(w, "API token is missing the required scope: "+scope, http.StatusForbidden)
+		return
+	}
+
+	if !checkEmailVerification(w, r, token.UserID) {
+		return
+	}
+
+	// Tambahkan user ID dan token ke konteks request
+	ctx := context.WithValue(r.Context(), "userID", token.UserID)
+	ctx = context.WithValue(ctx, "apiToken", token)
+	ctx = context.WithValue(ctx, "authMethod", AuthMethodAPIToken)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// checkEmailVerification membatasi akun yang emailnya belum terverifikasi ke rute tertentu
+// (jika kebijakan aktif). Mengembalikan false jika respons error sudah dikirim.
+func checkEmailVerification(w http.ResponseWriter, r *http.Request, userID uint) bool {
+	if !services.EmailVerificationRequired() || services.UnverifiedAllowedRoute(r.URL.Path) {
+		return true
+	}
+
+	verified, err := services.IsEmailVerified(userID)
+	if err != nil {
+		http.Error(w, "Failed to validate token", http.StatusInternalServerError)
+		return false
+	}
+	if !verified {
+		http.Error(w, "Email address is not verified", http.StatusForbidden)
+		return false
+	}
+	return true
+}
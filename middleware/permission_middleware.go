@@ -0,0 +1,23 @@
+// middleware/permission_middleware.go
+package middleware
+
+import (
+	"net/http"
+
+	"main/models"
+)
+
+// RequirePermission membatasi handler hanya untuk pengguna yang role-nya memiliki permission tertentu.
+// Harus dipasang setelah AuthMiddleware yang menyimpan role ke konteks request.
+func RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, _ := r.Context().Value("role").(string)
+			if !models.RoleHasPermission(role, permission) {
+				http.Error(w, "You do not have permission to perform this action", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
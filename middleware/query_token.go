@@ -0,0 +1,37 @@
+// middleware/query_token.go
+package middleware
+
+import (
+	"net/http"
+
+	"main/services"
+)
+
+// AllowQueryToken mengizinkan token dikirim lewat query ?access_token= untuk klien yang tidak bisa
+// mengatur header Authorization (mis. EventSource di browser). Pasang hanya di rute yang membutuhkannya,
+// sebelum AuthMiddleware. Token dihapus dari URL agar tidak ikut tercatat oleh handler berikutnya.
+// Hanya access token JWT berumur pendek yang diterima: URL mudah bocor lewat log dan riwayat browser,
+// sehingga API token (yang berumur panjang) harus tetap dikirim di header.
+func AllowQueryToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		token := query.Get("access_token")
+		if token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if services.IsAPIToken(token) {
+			http.Error(w, "API tokens must be sent in the Authorization header", http.StatusUnauthorized)
+			return
+		}
+
+		r = r.Clone(r.Context())
+		if r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		query.Del("access_token")
+		r.URL.RawQuery = query.Encode()
+		next.ServeHTTP(w, r)
+	})
+}
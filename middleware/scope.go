@@ -0,0 +1,15 @@
+// middleware/scope.go
+package middleware
+
+import "github.com/gorilla/mux"
+
+// routeScopes memetakan rute ke scope yang dibutuhkan API token untuk mengaksesnya.
+// Hanya diisi saat setup router (sebelum server berjalan), jadi tidak perlu lock.
+var routeScopes = map[*mux.Route]string{}
+
+// RequireScope mengizinkan rute diakses dengan API token yang memiliki scope tertentu.
+// Rute yang tidak didaftarkan di sini hanya menerima JWT dari login biasa.
+func RequireScope(route *mux.Route, scope string) *mux.Route {
+	routeScopes[route] = scope
+	return route
+}
@@ -0,0 +1,50 @@
+// models/api_token.go
+package models
+
+import "time"
+
+// Scope yang bisa diberikan ke API token
+const (
+	ScopePreferencesRead  = "preferences:read"
+	ScopePreferencesWrite = "preferences:write"
+	ScopeUserRead         = "user:read"
+	ScopeAssistant        = "assistant"
+)
+
+// APITokenScopes adalah daftar semua scope yang valid untuk API token
+var APITokenScopes = []string{
+	ScopePreferencesRead,
+	ScopePreferencesWrite,
+	ScopeUserRead,
+	ScopeAssistant,
+}
+
+// APIToken merupakan personal access token milik pengguna untuk skrip dan integrasi.
+// Token mentah hanya ditampilkan sekali saat dibuat; yang disimpan hanya hash-nya.
+type APIToken struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index;not null" json:"user_id"`
+	Name       string     `gorm:"size:100;not null" json:"name"`
+	Prefix     string     `gorm:"size:16;not null" json:"prefix"` // awal token untuk membantu pengguna mengenali token
+	TokenHash  string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	Scopes     []string   `gorm:"serializer:json;type:text" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// TableName menentukan nama tabel untuk model APIToken
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+// HasScope memeriksa apakah token memiliki scope tertentu
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
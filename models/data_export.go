@@ -0,0 +1,30 @@
+// models/data_export.go
+package models
+
+import "time"
+
+// Status ekspor data pribadi
+const (
+	DataExportPending = "pending"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+)
+
+// DataExport menyimpan ekspor data pribadi yang dibuat di background untuk akun besar.
+// Hasilnya bisa diunduh lewat tautan dengan token acak (yang disimpan hanya hash-nya) sampai ExpiresAt.
+type DataExport struct {
+	ID                string     `gorm:"primaryKey;size:64" json:"id"`
+	UserID            uint       `gorm:"index;not null" json:"-"`
+	Status            string     `gorm:"size:20;not null" json:"status"`
+	Data              []byte     `json:"-"` // bundle JSON
+	DownloadTokenHash string     `gorm:"size:64;index" json:"-"`
+	Error             string     `gorm:"size:255" json:"error,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt         time.Time  `gorm:"index;not null" json:"expires_at"`
+}
+
+// TableName menentukan nama tabel untuk model DataExport
+func (DataExport) TableName() string {
+	return "data_exports"
+}
@@ -0,0 +1,40 @@
+// models/external_identity.go
+package models
+
+import "time"
+
+// ExternalIdentity menautkan akun pengguna dengan identitas di penyedia OIDC eksternal.
+// Pasangan (provider, subject) unik: satu identitas eksternal hanya bisa tertaut ke satu akun.
+type ExternalIdentity struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"index;not null" json:"user_id"`
+	Provider    string     `gorm:"size:50;not null;uniqueIndex:idx_external_identity_subject" json:"provider"`
+	Subject     string     `gorm:"size:255;not null;uniqueIndex:idx_external_identity_subject" json:"subject"` // klaim "sub" dari ID token
+	Email       string     `gorm:"size:255" json:"email"`                                                      // email terakhir yang dilaporkan penyedia (informasi saja)
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName menentukan nama tabel untuk model ExternalIdentity
+func (ExternalIdentity) TableName() string {
+	return "external_identities"
+}
+
+// OIDCAuthRequest menyimpan state login OIDC yang sedang berjalan (state, PKCE verifier, nonce).
+// Baris dihapus saat callback diproses sehingga state hanya bisa dipakai sekali.
+type OIDCAuthRequest struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	StateHash    string    `gorm:"size:64;uniqueIndex;not null" json:"-"` // SHA-256 dari parameter state
+	Provider     string    `gorm:"size:50;not null" json:"provider"`
+	CodeVerifier string    `gorm:"size:128;not null" json:"-"`
+	Nonce        string    `gorm:"size:64;not null" json:"-"`
+	UserID       *uint     `gorm:"index" json:"user_id,omitempty"` // terisi jika alur ini untuk menautkan identitas ke akun yang sudah login
+	ExpiresAt    time.Time `gorm:"index;not null" json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName menentukan nama tabel untuk model OIDCAuthRequest
+func (OIDCAuthRequest) TableName() string {
+	return "oidc_auth_requests"
+}
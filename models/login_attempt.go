@@ -0,0 +1,19 @@
+// models/login_attempt.go
+package models
+
+import "time"
+
+// LoginAttempt menyimpan jumlah login gagal per kunci (username atau IP) untuk deployment multi-instance
+type LoginAttempt struct {
+	Key           string     `gorm:"primaryKey;size:255" json:"key"` // contoh: "user:alice", "ip:203.0.113.7"
+	Failures      int        `gorm:"not null;default:0" json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	NextAllowedAt time.Time  `json:"next_allowed_at"` // backoff eksponensial
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TableName menentukan nama tabel untuk model LoginAttempt
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
@@ -0,0 +1,17 @@
+// models/magic_link.go
+package models
+
+import "time"
+
+// UsedMagicLink mencatat jti tautan login (magic link) yang sudah ditukar, sehingga tautan hanya bisa dipakai sekali.
+// Entri bisa dihapus setelah ExpiresAt karena tautan yang kedaluwarsa sudah ditolak oleh validasi token.
+type UsedMagicLink struct {
+	JTI       string    `gorm:"primaryKey;size:64" json:"jti"`
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`
+	UsedAt    time.Time `gorm:"not null" json:"used_at"`
+}
+
+// TableName menentukan nama tabel untuk model UsedMagicLink
+func (UsedMagicLink) TableName() string {
+	return "used_magic_links"
+}
@@ -0,0 +1,19 @@
+// models/password_reset_token.go
+package models
+
+import "time"
+
+// PasswordResetToken menyimpan token reset password (dalam bentuk hash) yang hanya bisa dipakai sekali
+type PasswordResetToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null" json:"-"` // SHA-256 dari token mentah
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName menentukan nama tabel untuk model PasswordResetToken
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
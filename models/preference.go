@@ -0,0 +1,311 @@
+// models/preference.go
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+)
+
+// Tipe nilai preferensi
+const (
+	PreferenceTypeString  = "string"
+	PreferenceTypeBoolean = "boolean"
+	PreferenceTypeInteger = "integer"
+	PreferenceTypeNumber  = "number"
+	PreferenceTypeEnum    = "enum"
+)
+
+// PreferenceDefinition mendeskripsikan satu preferensi yang bisa diatur pengguna. Menambah preferensi baru
+// cukup dengan mendaftarkan definisi di sini; tidak perlu perubahan skema database atau handler.
+type PreferenceDefinition struct {
+	Key           string      `json:"key"`
+	Type          string      `json:"type"`
+	Description   string      `json:"description"`
+	Default       interface{} `json:"default"`
+	AllowedValues []string    `json:"allowed_values,omitempty"` // untuk tipe enum
+	Minimum       *float64    `json:"minimum,omitempty"`        // untuk tipe integer dan number
+	Maximum       *float64    `json:"maximum,omitempty"`        // untuk tipe integer dan number
+	MaxLength     int         `json:"max_length,omitempty"`     // untuk tipe string
+
+	// Canonicalize (opsional) mengubah ejaan lain ke nilai kanonik sebelum divalidasi, mis. tag BCP 47 "en-US" menjadi "english"
+	Canonicalize func(value string) string `json:"-"`
+
+	// Label dan kata kunci dipakai asisten untuk memahami perintah bahasa natural
+	Label         string              `json:"label"`
+	Keywords      []string            `json:"-"` // kata yang merujuk ke preferensi ini (mis. "theme", "tema")
+	ValueKeywords map[string][]string `json:"-"` // kata untuk setiap nilai enum (mis. "dark": "gelap")
+}
+
+// PreferenceValueError menjelaskan mengapa sebuah nilai ditolak oleh definisi preferensi
+type PreferenceValueError struct {
+	Code    string
+	Message string
+}
+
+// Error mengembalikan pesan error
+func (e *PreferenceValueError) Error() string {
+	return e.Message
+}
+
+// Normalize memvalidasi nilai hasil decode JSON terhadap definisi dan mengembalikan nilai dalam tipe Go-nya:
+// string, bool, int64 atau float64
+func (d PreferenceDefinition) Normalize(value interface{}) (interface{}, error) {
+	switch d.Type {
+	case PreferenceTypeString, PreferenceTypeEnum:
+		s, ok := value.(string)
+		if !ok {
+			return nil, &PreferenceValueError{Code: "invalid_type", Message: "must be a string"}
+		}
+		if d.Canonicalize != nil {
+			s = d.Canonicalize(s)
+		}
+		if d.Type == PreferenceTypeEnum {
+			// Nilai enum dicocokkan tanpa membedakan huruf besar/kecil lalu disimpan dalam ejaan resminya
+			allowed, ok := matchAllowedValue(d.AllowedValues, s)
+			if !ok {
+				return nil, &PreferenceValueError{Code: "invalid_value", Message: fmt.Sprintf("must be one of %v", d.AllowedValues)}
+			}
+			s = allowed
+		}
+		if d.MaxLength > 0 && utf8.RuneCountInString(s) > d.MaxLength {
+			return nil, &PreferenceValueError{Code: "too_long", Message: fmt.Sprintf("must be at most %d characters long", d.MaxLength)}
+		}
+		return s, nil
+
+	case PreferenceTypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, &PreferenceValueError{Code: "invalid_type", Message: "must be a boolean"}
+		}
+		return b, nil
+
+	case PreferenceTypeInteger, PreferenceTypeNumber:
+		var n float64
+		switch v := value.(type) {
+		case float64:
+			n = v
+		case int64:
+			n = float64(v)
+		case int:
+			n = float64(v)
+		case json.Number:
+			f, err := v.Float64()
+			if err != nil {
+				return nil, &PreferenceValueError{Code: "invalid_type", Message: "must be a number"}
+			}
+			n = f
+		default:
+			return nil, &PreferenceValueError{Code: "invalid_type", Message: "must be a number"}
+		}
+		if d.Type == PreferenceTypeInteger && n != math.Trunc(n) {
+			return nil, &PreferenceValueError{Code: "invalid_type", Message: "must be an integer"}
+		}
+		if d.Minimum != nil && n < *d.Minimum {
+			return nil, &PreferenceValueError{Code: "out_of_range", Message: fmt.Sprintf("must be at least %v", *d.Minimum)}
+		}
+		if d.Maximum != nil && n > *d.Maximum {
+			return nil, &PreferenceValueError{Code: "out_of_range", Message: fmt.Sprintf("must be at most %v", *d.Maximum)}
+		}
+		if d.Type == PreferenceTypeInteger {
+			return int64(n), nil
+		}
+		return n, nil
+	}
+	return nil, &PreferenceValueError{Code: "invalid_definition", Message: "unsupported preference type " + d.Type}
+}
+
+// Decode mengubah nilai tersimpan (JSON) menjadi nilai dalam tipe Go-nya
+func (d PreferenceDefinition) Decode(raw string) (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return nil, err
+	}
+	return d.Normalize(value)
+}
+
+// reservedPreferenceKeys adalah field metadata di JSON UserPreferences yang tidak boleh dipakai sebagai key
+var reservedPreferenceKeys = []string{"id", "user_id", "version", "created_at", "updated_at"}
+
+// IsPreferenceMetadataKey memeriksa apakah key adalah field metadata (hanya-baca) di JSON UserPreferences
+func IsPreferenceMetadataKey(key string) bool {
+	return containsString(reservedPreferenceKeys, key)
+}
+
+var (
+	preferenceRegistryMu sync.RWMutex
+	preferenceRegistry   = map[string]PreferenceDefinition{}
+)
+
+// RegisterPreference mendaftarkan definisi preferensi. Default-nya harus lolos validasi definisi itu sendiri.
+func RegisterPreference(def PreferenceDefinition) error {
+	if def.Key == "" {
+		return fmt.Errorf("preference key is required")
+	}
+	if containsString(reservedPreferenceKeys, def.Key) {
+		return fmt.Errorf("preference key %q is reserved", def.Key)
+	}
+	if def.Label == "" {
+		def.Label = def.Key
+	}
+	normalized, err := def.Normalize(def.Default)
+	if err != nil {
+		return fmt.Errorf("invalid default for preference %q: %w", def.Key, err)
+	}
+	def.Default = normalized
+
+	preferenceRegistryMu.Lock()
+	defer preferenceRegistryMu.Unlock()
+	if _, exists := preferenceRegistry[def.Key]; exists {
+		return fmt.Errorf("preference %q is already registered", def.Key)
+	}
+	preferenceRegistry[def.Key] = def
+	return nil
+}
+
+// MustRegisterPreference seperti RegisterPreference tetapi panic jika definisi tidak valid (untuk definisi bawaan)
+func MustRegisterPreference(def PreferenceDefinition) {
+	if err := RegisterPreference(def); err != nil {
+		panic(err)
+	}
+}
+
+// LookupPreference mencari definisi preferensi berdasarkan key
+func LookupPreference(key string) (PreferenceDefinition, bool) {
+	preferenceRegistryMu.RLock()
+	defer preferenceRegistryMu.RUnlock()
+	def, ok := preferenceRegistry[key]
+	return def, ok
+}
+
+// PreferenceDefinitions mengembalikan semua definisi preferensi, diurutkan berdasarkan key
+func PreferenceDefinitions() []PreferenceDefinition {
+	preferenceRegistryMu.RLock()
+	defer preferenceRegistryMu.RUnlock()
+	defs := make([]PreferenceDefinition, 0, len(preferenceRegistry))
+	for _, def := range preferenceRegistry {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Key < defs[j].Key })
+	return defs
+}
+
+// PreferenceRegistryFingerprint mengembalikan hash pendek dari bagian registry yang memengaruhi representasi
+// preferensi (key, tipe, default dan batasan nilai), sehingga berubah saat preferensi ditambah atau default diganti
+func PreferenceRegistryFingerprint() string {
+	hash := sha256.New()
+	for _, def := range PreferenceDefinitions() {
+		// Deskripsi dan label tidak ikut, karena tidak muncul di representasi preferensi pengguna
+		encoded, _ := json.Marshal([]interface{}{def.Key, def.Type, def.Default, def.AllowedValues, def.Minimum, def.Maximum, def.MaxLength})
+		hash.Write(encoded)
+		hash.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+// Preferensi bawaan (dulu kolom tetap di tabel user_preferences)
+func init() {
+	MustRegisterPreference(PreferenceDefinition{
+		Key:           "theme",
+		Type:          PreferenceTypeEnum,
+		Description:   "Color theme of the user interface",
+		Default:       "light",
+		AllowedValues: []string{"light", "dark"},
+		Label:         "theme",
+		Keywords:      []string{"theme", "tema", "mode"},
+		ValueKeywords: map[string][]string{
+			"light": {"light", "terang"},
+			"dark":  {"dark", "gelap"},
+		},
+	})
+	MustRegisterPreference(PreferenceDefinition{
+		Key:           "language",
+		Type:          PreferenceTypeEnum,
+		Description:   "Language of the user interface and assistant replies; BCP 47 tags such as \"en-US\" are accepted",
+		Default:       "english",
+		AllowedValues: []string{"english", "spanish", "indonesia"},
+		Canonicalize:  canonicalLanguage,
+		Label:         "language",
+		Keywords:      []string{"language", "bahasa"},
+		ValueKeywords: map[string][]string{
+			"english":   {"english", "inggris"},
+			"spanish":   {"spanish", "spanyol", "español"},
+			"indonesia": {"indonesia", "indonesian"},
+		},
+	})
+	MustRegisterPreference(PreferenceDefinition{
+		Key:         "notifications",
+		Type:        PreferenceTypeBoolean,
+		Description: "Whether the user receives notifications",
+		Default:     true,
+		Label:       "notifications",
+		Keywords:    []string{"notification", "notifications", "notifikasi"},
+	})
+}
+
+// PreferenceValue menyimpan satu nilai preferensi milik pengguna sebagai JSON.
+// Preferensi yang belum pernah diatur tidak memiliki baris dan memakai default dari definisinya.
+type PreferenceValue struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	UserID    uint      `gorm:"uniqueIndex:idx_preference_value_user_key;not null" json:"-"`
+	Key       string    `gorm:"size:64;uniqueIndex:idx_preference_value_user_key;not null" json:"key"`
+	Value     string    `gorm:"type:text;not null" json:"value"` // nilai ter-encode JSON
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName menentukan nama tabel untuk model PreferenceValue
+func (PreferenceValue) TableName() string {
+	return "preference_values"
+}
+
+// languageTagValues memetakan bahasa dasar BCP 47 ke nilai preferensi language
+var languageTagValues = map[string]string{
+	"en": "english",
+	"es": "spanish",
+	"id": "indonesia", // termasuk kode lama "in"
+}
+
+// canonicalLanguage menerima nama bahasa ("english") maupun tag BCP 47 ("EN", "en-US", "es-419", "id-ID")
+// dan mengembalikan nilai preferensi language. Tag yang tidak dikenali dikembalikan apa adanya agar ditolak validasi enum.
+func canonicalLanguage(value string) string {
+	value = strings.TrimSpace(value)
+	tag, err := language.Parse(value)
+	if err != nil {
+		return value
+	}
+	base, _ := tag.Base()
+	if mapped, ok := languageTagValues[base.String()]; ok {
+		return mapped
+	}
+	return value
+}
+
+// matchAllowedValue mencari nilai enum yang sama tanpa membedakan huruf besar/kecil
+func matchAllowedValue(allowed []string, value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	for _, candidate := range allowed {
+		if strings.EqualFold(candidate, value) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+// models/preference_history.go
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Sumber perubahan preferensi yang dicatat di riwayat
+const (
+	PreferenceSourceREST      = "rest"      // REST API oleh pengguna sendiri
+	PreferenceSourceAssistant = "assistant" // perintah bahasa natural lewat asisten
+	PreferenceSourceAdmin     = "admin"     // admin atau support melalui admin API
+	PreferenceSourceImport    = "import"    // impor massal atau migrasi data
+	PreferenceSourceRestore   = "restore"   // pemulihan ke versi sebelumnya
+)
+
+// PreferenceHistory mencatat satu perubahan nilai preferensi. Semua key yang berubah dalam satu
+// permintaan berbagi Version yang sama, sehingga pengguna bisa dipulihkan ke versi mana pun.
+// OldValue dan NewValue berisi JSON; nil berarti preferensi belum diatur (memakai default).
+type PreferenceHistory struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"index:idx_preference_history_user_version;not null"`
+	Version   int64     `gorm:"index:idx_preference_history_user_version;not null"`
+	Key       string    `gorm:"size:64;not null"`
+	OldValue  *string   `gorm:"type:text"`
+	NewValue  *string   `gorm:"type:text"`
+	Source    string    `gorm:"size:20;not null"`
+	ActorID   *uint     // pengguna yang melakukan perubahan (nil untuk proses sistem)
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// MarshalJSON menampilkan OldValue dan NewValue sebagai nilai JSON, bukan string
+func (h PreferenceHistory) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"id":         h.ID,
+		"version":    h.Version,
+		"key":        h.Key,
+		"old_value":  rawPreferenceJSON(h.OldValue),
+		"new_value":  rawPreferenceJSON(h.NewValue),
+		"source":     h.Source,
+		"actor_id":   h.ActorID,
+		"created_at": h.CreatedAt,
+	})
+}
+
+// rawPreferenceJSON mengubah nilai tersimpan menjadi json.RawMessage (null jika belum diatur)
+func rawPreferenceJSON(value *string) json.RawMessage {
+	if value == nil || !json.Valid([]byte(*value)) {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(*value)
+}
+
+// TableName menentukan nama tabel untuk model PreferenceHistory
+func (PreferenceHistory) TableName() string {
+	return "preference_histories"
+}
@@ -0,0 +1,18 @@
+// models/recovery_code.go
+package models
+
+import "time"
+
+// RecoveryCode menyimpan kode pemulihan 2FA sekali pakai (dalam bentuk hash)
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName menentukan nama tabel untuk model RecoveryCode
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
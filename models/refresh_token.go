@@ -0,0 +1,23 @@
+// models/refresh_token.go
+package models
+
+import "time"
+
+// RefreshToken menyimpan refresh token (dalam bentuk hash) yang diterbitkan untuk pengguna.
+// Setiap token termasuk dalam satu "family" yang dimulai saat login; setiap rotasi
+// membuat token baru di family yang sama sehingga pemakaian ulang token lama bisa dideteksi.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	FamilyID  string     `gorm:"size:64;index;not null" json:"family_id"`
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null" json:"-"` // SHA-256 dari token mentah
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`    // diisi saat token ditukar (rotasi)
+	RevokedAt *time.Time `json:"revoked_at,omitempty"` // diisi saat family dicabut
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName menentukan nama tabel untuk model RefreshToken
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
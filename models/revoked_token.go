@@ -0,0 +1,30 @@
+// models/revoked_token.go
+package models
+
+import "time"
+
+// RevokedToken menyimpan jti access token yang dicabut sebelum masa berlakunya habis
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey;size:64" json:"jti"`
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"` // baris boleh dihapus setelah waktu ini
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserTokenRevocation menyimpan batas waktu pencabutan massal (logout dari semua perangkat):
+// semua access token pengguna yang diterbitkan sebelum RevokedBefore dianggap tidak valid
+type UserTokenRevocation struct {
+	UserID        uint      `gorm:"primaryKey" json:"user_id"`
+	RevokedBefore time.Time `gorm:"not null" json:"revoked_before"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TableName menentukan nama tabel untuk model RevokedToken
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
+
+// TableName menentukan nama tabel untuk model UserTokenRevocation
+func (UserTokenRevocation) TableName() string {
+	return "user_token_revocations"
+}
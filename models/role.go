@@ -0,0 +1,51 @@
+// models/role.go
+package models
+
+// Role pengguna
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
+// Permission yang diperiksa oleh middleware RequirePermission
+const (
+	PermissionUsersRead         = "users:read"
+	PermissionUsersWrite        = "users:write"
+	PermissionUsersDelete       = "users:delete"
+	PermissionPreferencesManage = "preferences:manage"
+	PermissionLockoutsManage    = "lockouts:manage"
+)
+
+// RolePermissions memetakan setiap role ke permission yang dimilikinya
+var RolePermissions = map[string][]string{
+	RoleUser: {},
+	RoleSupport: {
+		PermissionUsersRead,
+		PermissionPreferencesManage,
+		PermissionLockoutsManage,
+	},
+	RoleAdmin: {
+		PermissionUsersRead,
+		PermissionUsersWrite,
+		PermissionUsersDelete,
+		PermissionPreferencesManage,
+		PermissionLockoutsManage,
+	},
+}
+
+// ValidRole memeriksa apakah role dikenal
+func ValidRole(role string) bool {
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+// RoleHasPermission memeriksa apakah role memiliki permission tertentu
+func RoleHasPermission(role, permission string) bool {
+	for _, p := range RolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
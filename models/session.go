@@ -0,0 +1,24 @@
+// models/session.go
+package models
+
+import "time"
+
+// Session adalah satu login di satu perangkat. ID sesi sama dengan family ID refresh token-nya
+// dan dicantumkan di klaim "sid" setiap access token, sehingga satu perangkat bisa dicabut tanpa
+// memengaruhi perangkat lain.
+type Session struct {
+	ID         string     `gorm:"primaryKey;size:64" json:"id"`
+	UserID     uint       `gorm:"index;not null" json:"user_id"`
+	DeviceName string     `gorm:"size:100" json:"device_name"`
+	UserAgent  string     `gorm:"size:512" json:"user_agent"`
+	IP         string     `gorm:"size:64" json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"index;not null" json:"expires_at"` // ikut diperpanjang setiap rotasi refresh token
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// TableName menentukan nama tabel untuk model Session
+func (Session) TableName() string {
+	return "sessions"
+}
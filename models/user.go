@@ -17,33 +17,109 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"main/utils"
+
 	"gorm.io/gorm"
 )
 
 // User merupakan model untuk tabel users di database
 type User struct {
-	ID          uint            `gorm:"primaryKey" json:"id"`
-	Username    string          `gorm:"size:100;uniqueIndex;not null" json:"username"`
-	Email       string          `gorm:"size:100;uniqueIndex;not null" json:"email"`
-	Password    string          `gorm:"size:255;not null" json:"-"` // Password tidak ditampilkan dalam JSON response
-	Preferences UserPreferences `gorm:"foreignKey:UserID" json:"preferences"`
-	CreatedAt   time.Time       `json:"created_at"`
-	UpdatedAt   time.Time       `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt  `gorm:"index" json:"-"`
+	ID                uint                 `gorm:"primaryKey" json:"id"`
+	Username          string               `gorm:"size:100;uniqueIndex;not null" json:"username"`
+	Email             string               `gorm:"size:100;uniqueIndex;not null" json:"email"`
+	UsernameCanonical string               `gorm:"size:100" json:"-"`                       // NFKC + huruf kecil; unique index dibuat oleh migrasi identitas
+	EmailCanonical    string               `gorm:"size:100" json:"-"`                       // NFKC + huruf kecil; unique index dibuat oleh migrasi identitas
+	PendingEmail      string               `gorm:"size:100" json:"pending_email,omitempty"` // email baru yang menunggu verifikasi sebelum dipakai
+	Password          string               `gorm:"size:255;not null" json:"-"`              // Password tidak ditampilkan dalam JSON response
+	Role              string               `gorm:"size:20;not null;default:'user'" json:"role"`
+	Disabled          bool                 `gorm:"default:false" json:"disabled"`
+	DisabledAt        *time.Time           `json:"disabled_at,omitempty"`
+	EmailVerified     bool                 `gorm:"default:false" json:"email_verified"`
+	EmailVerifiedAt   *time.Time           `json:"email_verified_at,omitempty"`
+	TOTPEnabled       bool                 `gorm:"default:false" json:"totp_enabled"`
+	TOTPSecret        string               `gorm:"size:64" json:"-"`   // secret base32; tersimpan sebelum 2FA aktif selama proses setup
+	TOTPLastCounter   int64                `gorm:"default:0" json:"-"` // langkah waktu terakhir yang dipakai, mencegah pemakaian ulang kode
+	Preferences       UserPreferences      `gorm:"foreignKey:UserID" json:"preferences"`
+	Identities        []ExternalIdentity   `gorm:"foreignKey:UserID" json:"identities,omitempty"` // identitas OIDC yang tertaut
+	Passkeys          []WebAuthnCredential `gorm:"foreignKey:UserID" json:"passkeys,omitempty"`   // kredensial WebAuthn
+	CreatedAt         time.Time            `json:"created_at"`
+	UpdatedAt         time.Time            `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt       `gorm:"index" json:"-"`
 }
 
-// UserPreferences menyimpan preferensi pengguna
+// UserPreferences adalah kumpulan preferensi pengguna. Barisnya hanya menyimpan metadata; nilai setiap
+// preferensi disimpan di preference_values dan didefinisikan oleh registry preferensi.
+// JSON-nya tetap datar seperti dulu ({"theme": "light", "language": "english", ...}).
 type UserPreferences struct {
-	ID            uint      `gorm:"primaryKey" json:"id"`
-	UserID        uint      `gorm:"uniqueIndex;not null" json:"user_id"`
-	Theme         string    `gorm:"size:20;default:'light'" json:"theme"`      // light/dark
-	Language      string    `gorm:"size:20;default:'english'" json:"language"` // english/spanish/indonesia/etc
-	Notifications bool      `gorm:"default:true" json:"notifications"`         // true/false
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID        uint              `gorm:"primaryKey"`
+	UserID    uint              `gorm:"uniqueIndex;not null"`
+	Values    []PreferenceValue `gorm:"foreignKey:UserID;references:UserID"`
+	Version   int64             `gorm:"not null;default:0"` // naik setiap kali ada nilai yang berubah; sama dengan versi terakhir di riwayat
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// DefaultPreferences mengembalikan preferensi awal untuk pengguna baru. Tidak ada nilai yang disimpan:
+// semua preferensi memakai default dari definisinya sampai pengguna mengubahnya.
+func DefaultPreferences(userID uint) UserPreferences {
+	return UserPreferences{
+		UserID:    userID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+// Get mengembalikan nilai preferensi pengguna, atau default dari definisinya jika belum diatur
+// (atau jika nilai tersimpan tidak lagi valid terhadap definisinya)
+func (p UserPreferences) Get(key string) interface{} {
+	def, ok := LookupPreference(key)
+	if !ok {
+		return nil
+	}
+	for _, stored := range p.Values {
+		if stored.Key != key {
+			continue
+		}
+		if value, err := def.Decode(stored.Value); err == nil {
+			return value
+		}
+		break
+	}
+	return def.Default
+}
+
+// Map mengembalikan nilai semua preferensi yang terdaftar
+func (p UserPreferences) Map() map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, def := range PreferenceDefinitions() {
+		values[def.Key] = p.Get(def.Key)
+	}
+	return values
+}
+
+// MarshalJSON menghasilkan bentuk datar: metadata ditambah satu field per preferensi
+func (p UserPreferences) MarshalJSON() ([]byte, error) {
+	body := p.Map()
+	body["id"] = p.ID
+	body["user_id"] = p.UserID
+	body["version"] = p.Version
+	body["created_at"] = p.CreatedAt
+	body["updated_at"] = p.UpdatedAt
+	return json.Marshal(body)
+}
+
+// BeforeSave mengisi kolom kanonik dari username dan email setiap kali user dibuat atau disimpan
+func (u *User) BeforeSave(tx *gorm.DB) error {
+	if u.Username != "" {
+		u.UsernameCanonical = utils.CanonicalIdentifier(u.Username)
+	}
+	if u.Email != "" {
+		u.EmailCanonical = utils.CanonicalIdentifier(u.Email)
+	}
+	return nil
 }
 
 // TableName menentukan nama tabel untuk model User
@@ -56,21 +132,33 @@ func (UserPreferences) TableName() string {
 	return "user_preferences"
 }
 
-// BeforeSave - hook yang dijalankan sebelum menyimpan user untuk hash password
-func (u *User) BeforeSave(tx *gorm.DB) error {
-	// Hash password jika password diubah dan tidak kosong
-	if u.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
-		if err != nil {
-			return err
-		}
-		u.Password = string(hashedPassword)
+// SetPassword meng-hash password baru dengan hasher default (Argon2id) dan menyimpannya di field Password.
+// Hashing hanya dilakukan di sini (bukan di hook save) supaya menyimpan ulang user
+// yang sudah dimuat dari database tidak meng-hash ulang hash yang tersimpan.
+func (u *User) SetPassword(password string) error {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return err
 	}
+	u.Password = hashedPassword
 	return nil
 }
 
 // CheckPassword membandingkan password yang diberikan dengan password hash yang tersimpan
 func (u *User) CheckPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
-	return err == nil
+	ok, _ := u.VerifyPassword(password)
+	return ok
+}
+
+// VerifyPassword membandingkan password dengan hash tersimpan (Argon2id maupun bcrypt lama).
+// needsRehash bernilai true jika password cocok tetapi hash-nya memakai algoritma atau parameter lama.
+func (u *User) VerifyPassword(password string) (ok bool, needsRehash bool) {
+	if u.Password == "" {
+		return false, false
+	}
+	ok, needsRehash, err := utils.VerifyPassword(password, u.Password)
+	if err != nil {
+		return false, false
+	}
+	return ok, needsRehash
 }
@@ -0,0 +1,50 @@
+// models/webauthn_credential.go
+package models
+
+import "time"
+
+// Tujuan challenge WebAuthn
+const (
+	WebAuthnCeremonyRegistration = "registration"
+	WebAuthnCeremonyLogin        = "login"
+)
+
+// WebAuthnCredential menyimpan passkey (kredensial WebAuthn) milik pengguna
+type WebAuthnCredential struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	UserID         uint       `gorm:"index;not null" json:"user_id"`
+	Name           string     `gorm:"size:100;not null" json:"name"`
+	CredentialID   string     `gorm:"size:1400;uniqueIndex;not null" json:"credential_id"` // base64url dari raw credential ID
+	PublicKey      []byte     `gorm:"not null" json:"-"`                                   // kunci publik dalam format COSE
+	Algorithm      int        `gorm:"not null" json:"algorithm"`                           // algoritma COSE (-7, -8, -257)
+	SignCount      uint32     `gorm:"not null;default:0" json:"sign_count"`
+	AAGUID         string     `gorm:"size:36" json:"aaguid"`
+	Transports     []string   `gorm:"serializer:json;type:text" json:"transports"`
+	BackupEligible bool       `gorm:"default:false" json:"backup_eligible"` // passkey tersinkron (mis. iCloud Keychain, Google Password Manager)
+	BackupState    bool       `gorm:"default:false" json:"backup_state"`
+	CloneWarning   bool       `gorm:"default:false" json:"clone_warning"` // sign count mundur: kemungkinan authenticator digandakan
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName menentukan nama tabel untuk model WebAuthnCredential
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
+
+// WebAuthnChallenge menyimpan challenge ceremony WebAuthn yang sedang berjalan.
+// Challenge dihapus saat dipakai sehingga hanya bisa diverifikasi sekali.
+type WebAuthnChallenge struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	ChallengeHash string    `gorm:"size:64;uniqueIndex;not null" json:"-"` // SHA-256 dari challenge (base64url)
+	Ceremony      string    `gorm:"size:20;not null" json:"ceremony"`      // registration/login
+	UserID        *uint     `gorm:"index" json:"user_id,omitempty"`        // kosong untuk login tanpa username (discoverable credential)
+	ExpiresAt     time.Time `gorm:"index;not null" json:"expires_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// TableName menentukan nama tabel untuk model WebAuthnChallenge
+func (WebAuthnChallenge) TableName() string {
+	return "webauthn_challenges"
+}
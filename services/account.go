@@ -0,0 +1,216 @@
+// services/account.go
+package services
+
+import (
+	"errors"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"main/config"
+	"main/models"
+	"main/utils"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidPassword dikembalikan jika password saat ini yang dikirim pengguna salah
+	ErrInvalidPassword = errors.New("current password is incorrect")
+	// ErrEmailTaken dikembalikan jika email baru sudah dipakai akun lain
+	ErrEmailTaken = errors.New("email already exists")
+	// ErrInvalidEmail dikembalikan jika email bukan satu alamat email yang valid
+	ErrInvalidEmail = errors.New("invalid email address")
+)
+
+// ValidateEmailAddress memastikan email adalah satu alamat polos (RFC 5322, tanpa nama tampilan)
+// yang muat di kolom email
+func ValidateEmailAddress(email string) error {
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Name != "" || address.Address != email || len(email) > 100 {
+		return ErrInvalidEmail
+	}
+	return nil
+}
+
+// ChangePassword mengganti password setelah password saat ini dikonfirmasi,
+// lalu mencabut semua sesi yang ada dan menerbitkan sesi baru untuk klien yang meminta
+func ChangePassword(userID uint, currentPassword, newPassword string, meta SessionMetadata) (*TokenPair, error) {
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	if !user.CheckPassword(currentPassword) {
+		return nil, ErrInvalidPassword
+	}
+
+	pctx := PasswordContext{Username: user.Username, Email: user.Email}
+	if err := ValidatePassword("new_password", newPassword, pctx); err != nil {
+		return nil, err
+	}
+
+	if err := user.SetPassword(newPassword); err != nil {
+		return nil, err
+	}
+	if err := config.DB.Model(&user).Update("password", user.Password).Error; err != nil {
+		return nil, err
+	}
+
+	if err := RevokeAllUserTokens(userID); err != nil {
+		return nil, err
+	}
+
+	SendMailAsync(MailMessage{
+		To:      user.Email,
+		Subject: "Your password was changed",
+		Body: "Hi " + user.Username + ",\n\n" +
+			"The password for your account was changed and all other sessions were signed out.\n\n" +
+			"If this wasn't you, reset your password immediately.",
+	})
+
+	return IssueTokens(userID, meta)
+}
+
+// ChangeEmail menyimpan email baru sebagai email tertunda setelah password dikonfirmasi dan mengirim
+// tautan verifikasi ke alamat tersebut. Email akun (untuk login, reset password, dan magic link)
+// baru berganti setelah tautan dikonfirmasi lewat VerifyEmail.
+func ChangeEmail(userID uint, password, newEmail string) (*models.User, error) {
+	if err := ValidateEmailAddress(newEmail); err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	if !user.CheckPassword(password) {
+		return nil, ErrInvalidPassword
+	}
+
+	// Mengirim email saat ini membatalkan perubahan yang masih tertunda
+	if newEmail == user.Email {
+		if user.PendingEmail != "" {
+			if err := config.DB.Model(&models.User{}).Where("id = ?", userID).Update("pending_email", "").Error; err != nil {
+				return nil, err
+			}
+			user.PendingEmail = ""
+		}
+		return &user, nil
+	}
+
+	if err := checkEmailAvailable(userID, newEmail); err != nil {
+		return nil, err
+	}
+
+	if err := config.DB.Model(&models.User{}).Where("id = ?", userID).Update("pending_email", newEmail).Error; err != nil {
+		return nil, err
+	}
+	user.PendingEmail = newEmail
+
+	token, err := utils.GeneratePurposeToken(utils.PurposeEmailVerification, user.ID, newEmail, EmailVerificationTTL())
+	if err != nil {
+		return nil, err
+	}
+
+	SendMailAsync(MailMessage{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: "Hi " + user.Username + ",\n\n" +
+			"Please confirm your new email address by opening the link below. The link expires in " + EmailVerificationTTL().String() + ".\n" +
+			"Your account keeps using " + user.Email + " until the change is confirmed.\n\n" +
+			AppURL("/verify-email?token="+url.QueryEscape(token)) + "\n\n" +
+			"If you did not request this change, you can ignore this email.",
+	})
+
+	SendMailAsync(MailMessage{
+		To:      user.Email,
+		Subject: "Email change requested",
+		Body: "Hi " + user.Username + ",\n\n" +
+			"A request was made to change the email address for your account to " + newEmail + ".\n" +
+			"The change takes effect only after the new address is confirmed.\n\n" +
+			"If this wasn't you, change your password and contact support immediately.",
+	})
+
+	return &user, nil
+}
+
+// confirmPendingEmail menerapkan email tertunda setelah tautan verifikasinya dikonfirmasi
+func confirmPendingEmail(user *models.User) error {
+	if err := checkEmailAvailable(user.ID, user.PendingEmail); err != nil {
+		return err
+	}
+
+	oldEmail := user.Email
+	now := time.Now()
+	// Syarat pending_email mencegah perubahan ganda jika tautan yang sama dibuka bersamaan
+	result := config.DB.Model(&models.User{}).Where("id = ? AND pending_email = ?", user.ID, user.PendingEmail).Updates(map[string]interface{}{
+		"email":             user.PendingEmail,
+		"email_canonical":   utils.CanonicalIdentifier(user.PendingEmail),
+		"pending_email":     "",
+		"email_verified":    true,
+		"email_verified_at": now,
+		"updated_at":        now,
+	})
+	if result.Error != nil {
+		if isUniqueViolation(result.Error) {
+			return ErrEmailTaken
+		}
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidVerificationToken
+	}
+
+	user.Email = user.PendingEmail
+	user.EmailCanonical = utils.CanonicalIdentifier(user.PendingEmail)
+	user.PendingEmail = ""
+	user.EmailVerified = true
+	user.EmailVerifiedAt = &now
+
+	SendMailAsync(MailMessage{
+		To:      oldEmail,
+		Subject: "Your email address was changed",
+		Body: "Hi " + user.Username + ",\n\n" +
+			"The email address for your account was changed to " + user.Email + ".\n\n" +
+			"If this wasn't you, contact support immediately.",
+	})
+	return nil
+}
+
+// checkEmailAvailable memastikan email belum dipakai akun lain sebagai email maupun username,
+// termasuk akun yang menunggu penghapusan permanen
+func checkEmailAvailable(userID uint, email string) error {
+	canonical := utils.CanonicalIdentifier(email)
+	var existing models.User
+	result := config.DB.Unscoped().
+		Where("(email_canonical = ? OR username_canonical = ?) AND id <> ?", canonical, canonical, userID).
+		First(&existing)
+	if result.Error == nil {
+		return ErrEmailTaken
+	}
+	if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return result.Error
+	}
+	return nil
+}
+
+// isUniqueViolation memeriksa apakah error berasal dari pelanggaran unique index di Postgres
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// UpgradePasswordHash meng-hash ulang password yang baru saja terverifikasi dengan hasher default.
+// Update hanya berlaku jika hash tersimpan belum berubah, agar tidak menimpa password yang diganti bersamaan.
+func UpgradePasswordHash(user *models.User, password string) error {
+	oldHash := user.Password
+	if err := user.SetPassword(password); err != nil {
+		return err
+	}
+	return config.DB.Model(&models.User{}).
+		Where("id = ? AND password = ?", user.ID, oldHash).
+		Update("password", user.Password).Error
+}
@@ -0,0 +1,150 @@
+// services/account_deletion.go
+package services
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"time"
+
+	"main/config"
+	"main/models"
+	"main/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrPasswordNotSet dikembalikan jika akun tanpa password (SSO, passkey, magic link) meminta penghapusan akun
+	ErrPasswordNotSet = errors.New("account has no password; set one with the password reset flow before deleting the account")
+	// ErrInvalidRestoreToken dikembalikan jika tautan pemulihan tidak valid, kedaluwarsa, atau akun sudah dihapus permanen
+	ErrInvalidRestoreToken = errors.New("invalid or expired restore link")
+	// ErrAccountPendingDeletion dikembalikan saat login ke akun yang sedang menunggu penghapusan permanen
+	ErrAccountPendingDeletion = errors.New("account is scheduled for deletion; use the restore link sent by email to recover it")
+)
+
+// AccountDeletionGracePeriod mengembalikan masa tenggang sebelum akun yang dihapus dihapus permanen (default 30 hari)
+func AccountDeletionGracePeriod() time.Duration {
+	return config.GetEnvDuration("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour)
+}
+
+// DeleteAccount menandai akun sebagai dihapus (soft delete) setelah password dikonfirmasi. Semua sesi dan
+// API token langsung berhenti berlaku; data dihapus permanen oleh purger setelah masa tenggang berakhir.
+// Mengembalikan waktu penghapusan permanen.
+func DeleteAccount(userID uint, password string) (time.Time, error) {
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return time.Time{}, err
+	}
+
+	if user.Password == "" {
+		return time.Time{}, ErrPasswordNotSet
+	}
+	if !user.CheckPassword(password) {
+		return time.Time{}, ErrInvalidPassword
+	}
+
+	if err := config.DB.Delete(&user).Error; err != nil {
+		return time.Time{}, err
+	}
+	if err := RevokeAllUserTokens(userID); err != nil {
+		return time.Time{}, err
+	}
+
+	purgeAt := time.Now().Add(AccountDeletionGracePeriod())
+	token, err := utils.GeneratePurposeToken(utils.PurposeAccountRestore, user.ID, user.Email, AccountDeletionGracePeriod())
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	SendMailAsync(MailMessage{
+		To:      user.Email,
+		Subject: "Your account is scheduled for deletion",
+		Body: "Hi " + user.Username + ",\n\n" +
+			"Your account and all of its data will be permanently deleted on " + purgeAt.Format(time.RFC1123) + ".\n\n" +
+			"Changed your mind? Open the link below before then to restore your account:\n\n" +
+			AppURL("/restore-account?token="+url.QueryEscape(token)) + "\n\n" +
+			"If you did not request this, restore your account and change your password immediately.",
+	})
+	return purgeAt, nil
+}
+
+// RestoreAccount membatalkan penghapusan akun yang masih dalam masa tenggang berdasarkan token dari tautan pemulihan
+func RestoreAccount(token string) (*models.User, error) {
+	claims, err := utils.ValidatePurposeToken(token, utils.PurposeAccountRestore)
+	if err != nil {
+		return nil, ErrInvalidRestoreToken
+	}
+
+	var user models.User
+	result := config.DB.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", claims.UserID).First(&user)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRestoreToken
+		}
+		return nil, result.Error
+	}
+
+	// Token dari penghapusan sebelumnya (akun sudah pernah dipulihkan lalu dihapus lagi) tidak berlaku
+	deletedAt := user.DeletedAt.Time
+	if user.Email != claims.Email || claims.IssuedAt == nil || claims.IssuedAt.Time.Before(deletedAt.Truncate(time.Second)) {
+		return nil, ErrInvalidRestoreToken
+	}
+	if time.Since(deletedAt) > AccountDeletionGracePeriod() {
+		return nil, ErrInvalidRestoreToken
+	}
+
+	if err := config.DB.Unscoped().Model(&user).Update("deleted_at", nil).Error; err != nil {
+		return nil, err
+	}
+	user.DeletedAt = gorm.DeletedAt{}
+
+	SendMailAsync(MailMessage{
+		To:      user.Email,
+		Subject: "Your account was restored",
+		Body: "Hi " + user.Username + ",\n\n" +
+			"Your account was restored and will not be deleted.",
+	})
+	return &user, nil
+}
+
+// PurgeDeletedAccounts menghapus permanen akun yang masa tenggangnya sudah berakhir, beserta semua datanya.
+// Setelah dihapus permanen, username dan email akun tersebut bisa dipakai lagi.
+func PurgeDeletedAccounts() (int, error) {
+	var userIDs []uint
+	err := config.DB.Unscoped().Model(&models.User{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", time.Now().Add(-AccountDeletionGracePeriod())).
+		Pluck("id", &userIDs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, userID := range userIDs {
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			return purgeUserData(tx, userID)
+		})
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// StartAccountPurger menjalankan PurgeDeletedAccounts secara berkala di background
+func StartAccountPurger(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			purged, err := PurgeDeletedAccounts()
+			if err != nil {
+				log.Printf("Failed to purge deleted accounts: %v", err)
+			}
+			if purged > 0 {
+				log.Printf("Purged %d deleted account(s)", purged)
+			}
+		}
+	}()
+}
@@ -0,0 +1,36 @@
+// services/account_test.go
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateEmailAddress(t *testing.T) {
+	tests := []struct {
+		email   string
+		wantErr bool
+	}{
+		{email: "alice@example.com"},
+		{email: "alice+tag@sub.example.co.id"},
+		{email: "", wantErr: true},
+		{email: "alice", wantErr: true},
+		{email: "alice@", wantErr: true},
+		{email: "@example.com", wantErr: true},
+		{email: "Alice <alice@example.com>", wantErr: true},
+		{email: "<alice@example.com>", wantErr: true},
+		{email: " alice@example.com", wantErr: true},
+		{email: "alice@example.com, bob@example.com", wantErr: true},
+		{email: "alice@example.com\r\nBcc: bob@example.com", wantErr: true},
+		{email: strings.Repeat("a", 90) + "@example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			err := ValidateEmailAddress(tt.email)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEmailAddress(%q) error = %v, wantErr %v", tt.email, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// services/admin.go
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"main/config"
+	"main/models"
+	"main/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUserNotFound dikembalikan jika pengguna yang diminta tidak ada
+	ErrUserNotFound = errors.New("user not found")
+	// ErrInvalidRole dikembalikan jika role tidak dikenal
+	ErrInvalidRole = errors.New("unknown role")
+)
+
+// UserListResult berisi satu halaman hasil pencarian pengguna
+type UserListResult struct {
+	Users    []models.User `json:"users"`
+	Total    int64         `json:"total"`
+	Page     int           `json:"page"`
+	PageSize int           `json:"page_size"`
+}
+
+// ListUsers mencari pengguna berdasarkan username/email (tidak peka huruf besar/kecil) dengan paginasi
+func ListUsers(query string, page, pageSize int) (*UserListResult, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	db := config.DB.Model(&models.User{})
+	if query = strings.TrimSpace(query); query != "" {
+		pattern := "%" + escapeLike(strings.ToLower(query)) + "%"
+		db = db.Where(`LOWER(username) LIKE ? ESCAPE '\' OR LOWER(email) LIKE ? ESCAPE '\'`, pattern, pattern)
+	}
+
+	result := &UserListResult{Users: []models.User{}, Page: page, PageSize: pageSize}
+	if err := db.Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+	err := db.Preload("Preferences.Values").
+		Order("id").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&result.Users).Error
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// likeEscaper meloloskan karakter khusus pola LIKE agar dicocokkan apa adanya
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike meloloskan \, % dan _ pada teks pencarian untuk dipakai dengan LIKE ... ESCAPE '\'
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// GetUser mengambil pengguna beserta preferensinya
+func GetUser(userID uint) (*models.User, error) {
+	var user models.User
+	result := config.DB.Preload("Preferences.Values").First(&user, userID)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, result.Error
+	}
+	return &user, nil
+}
+
+// SetUserDisabled menonaktifkan atau mengaktifkan kembali akun; menonaktifkan juga mencabut semua sesinya
+func SetUserDisabled(userID uint, disabled bool) (*models.User, error) {
+	if _, err := GetUser(userID); err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{"disabled": disabled, "disabled_at": nil}
+	if disabled {
+		updates["disabled_at"] = time.Now()
+	}
+	if err := config.DB.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	if disabled {
+		if err := RevokeAllUserTokens(userID); err != nil {
+			return nil, err
+		}
+	}
+	return GetUser(userID)
+}
+
+// SetUserRole mengganti role pengguna; token lama dicabut agar klaim role yang usang tidak berlaku lagi
+func SetUserRole(userID uint, role string) (*models.User, error) {
+	if !models.ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	if _, err := GetUser(userID); err != nil {
+		return nil, err
+	}
+
+	if err := config.DB.Model(&models.User{}).Where("id = ?", userID).Update("role", role).Error; err != nil {
+		return nil, err
+	}
+	if err := RevokeAllUserTokens(userID); err != nil {
+		return nil, err
+	}
+	return GetUser(userID)
+}
+
+// DeleteUser menghapus permanen pengguna beserta semua datanya, termasuk akun yang masih dalam masa tenggang penghapusan
+func DeleteUser(userID uint) error {
+	var user models.User
+	if err := config.DB.Unscoped().Select("id").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	// Cabut sesi lebih dulu supaya access token yang masih hidup langsung ditolak
+	if err := RevokeAllUserTokens(userID); err != nil {
+		return err
+	}
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		return purgeUserData(tx, userID)
+	})
+}
+
+// BootstrapAdmins memberi role admin ke username yang tercantum di ADMIN_USERNAMES (dipisahkan koma)
+func BootstrapAdmins() {
+	usernames := config.GetEnvList("ADMIN_USERNAMES", nil)
+	if len(usernames) == 0 {
+		return
+	}
+
+	canonical := make([]string, 0, len(usernames))
+	for _, username := range usernames {
+		canonical = append(canonical, utils.CanonicalIdentifier(username))
+	}
+
+	result := config.DB.Model(&models.User{}).
+		Where("username_canonical IN ? AND role <> ?", canonical, models.RoleAdmin).
+		Update("role", models.RoleAdmin)
+	if result.Error != nil {
+		log.Printf("Failed to bootstrap admin users: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Promoted %d user(s) from ADMIN_USERNAMES to admin", result.RowsAffected)
+	}
+}
@@ -0,0 +1,194 @@
+// services/api_tokens.go
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"main/config"
+	"main/models"
+	"main/utils"
+
+	"gorm.io/gorm"
+)
+
+// APITokenPrefix menandai token sebagai personal access token (bukan JWT)
+const APITokenPrefix = "pat_"
+
+var (
+	// ErrInvalidAPIToken dikembalikan jika API token tidak dikenal atau kedaluwarsa
+	ErrInvalidAPIToken = errors.New("invalid or expired API token")
+	// ErrAPITokenNotFound dikembalikan jika token yang diminta tidak ada atau bukan milik pengguna
+	ErrAPITokenNotFound = errors.New("API token not found")
+	// ErrInvalidScope dikembalikan jika scope yang diminta tidak dikenal
+	ErrInvalidScope = errors.New("unknown scope")
+)
+
+// lastUsedResolution membatasi seberapa sering last_used_at ditulis ke database
+const lastUsedResolution = time.Minute
+
+// IsAPIToken memeriksa apakah bearer token berformat personal access token
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// CreateAPIToken membuat API token baru dan mengembalikan token mentah (hanya sekali)
+func CreateAPIToken(userID uint, name string, scopes []string, expiresAt *time.Time) (string, *models.APIToken, error) {
+	for _, scope := range scopes {
+		if !validScope(scope) {
+			return "", nil, ErrInvalidScope
+		}
+	}
+
+	random, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	rawToken := APITokenPrefix + random
+
+	token := models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    rawToken[:12],
+		TokenHash: utils.HashToken(rawToken),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := config.DB.Create(&token).Error; err != nil {
+		return "", nil, err
+	}
+	return rawToken, &token, nil
+}
+
+// AuthenticateAPIToken mencari API token berdasarkan token mentah dan mencatat waktu pemakaiannya
+func AuthenticateAPIToken(rawToken string) (*models.APIToken, error) {
+	var token models.APIToken
+	result := config.DB.Where("token_hash = ?", utils.HashToken(rawToken)).First(&token)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIToken
+		}
+		return nil, result.Error
+	}
+
+	now := time.Now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, ErrInvalidAPIToken
+	}
+
+	// Token milik akun yang dinonaktifkan tidak diterima
+	var user models.User
+	if err := config.DB.Select("id", "disabled").First(&user, token.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIToken
+		}
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, ErrInvalidAPIToken
+	}
+
+	// last_used_at cukup akurat per menit; hindari write di setiap request
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > lastUsedResolution {
+		if err := config.DB.Model(&models.APIToken{}).Where("id = ?", token.ID).Update("last_used_at", now).Error; err != nil {
+			return nil, err
+		}
+		token.LastUsedAt = &now
+	}
+	return &token, nil
+}
+
+// APITokenRevoked memeriksa ulang apakah API token yang sudah diautentikasi kini dihapus, kedaluwarsa,
+// atau milik akun yang dinonaktifkan (untuk koneksi yang berumur panjang seperti stream SSE)
+func APITokenRevoked(token *models.APIToken) (bool, error) {
+	var current models.APIToken
+	result := config.DB.Where("id = ?", token.ID).Limit(1).Find(&current)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return true, nil
+	}
+	if current.ExpiresAt != nil && time.Now().After(*current.ExpiresAt) {
+		return true, nil
+	}
+
+	var user models.User
+	if err := config.DB.Select("id", "disabled").First(&user, current.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true, nil
+		}
+		return false, err
+	}
+	return user.Disabled, nil
+}
+
+// ListAPITokens mengambil semua API token milik pengguna
+func ListAPITokens(userID uint) ([]models.APIToken, error) {
+	tokens := []models.APIToken{}
+	err := config.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// GetAPIToken mengambil satu API token milik pengguna
+func GetAPIToken(userID, tokenID uint) (*models.APIToken, error) {
+	var token models.APIToken
+	result := config.DB.Where("id = ? AND user_id = ?", tokenID, userID).First(&token)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrAPITokenNotFound
+		}
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+// UpdateAPIToken mengganti nama dan/atau scope API token milik pengguna
+func UpdateAPIToken(userID, tokenID uint, name *string, scopes []string) (*models.APIToken, error) {
+	token, err := GetAPIToken(userID, tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != nil {
+		token.Name = *name
+	}
+	if scopes != nil {
+		for _, scope := range scopes {
+			if !validScope(scope) {
+				return nil, ErrInvalidScope
+			}
+		}
+		token.Scopes = scopes
+	}
+
+	if err := config.DB.Save(token).Error; err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// DeleteAPIToken menghapus (mencabut) API token milik pengguna
+func DeleteAPIToken(userID, tokenID uint) error {
+	result := config.DB.Where("id = ? AND user_id = ?", tokenID, userID).Delete(&models.APIToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// validScope memeriksa apakah scope termasuk daftar scope yang dikenal
+func validScope(scope string) bool {
+	for _, known := range models.APITokenScopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
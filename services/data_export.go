@@ -0,0 +1,282 @@
+// services/data_export.go
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"main/config"
+	"main/models"
+	"main/utils"
+
+	"gorm.io/gorm"
+)
+
+// DataExportFormatVersion dinaikkan setiap kali struktur DataExportBundle berubah secara tidak kompatibel
+const DataExportFormatVersion = 1
+
+var (
+	// ErrDataExportNotFound dikembalikan jika ekspor tidak ada, bukan milik pengguna, atau sudah kedaluwarsa
+	ErrDataExportNotFound = errors.New("export not found or expired")
+	// ErrDataExportNotReady dikembalikan jika ekspor belum selesai dibuat
+	ErrDataExportNotReady = errors.New("export is not ready yet")
+)
+
+// DataExportBundle adalah isi ekspor data pribadi: semua data yang disimpan layanan tentang pengguna
+type DataExportBundle struct {
+	FormatVersion     int                        `json:"format_version"`
+	ExportedAt        time.Time                  `json:"exported_at"`
+	User              models.User                `json:"user"` // termasuk preferensi, identitas tertaut dan passkey
+	PreferenceHistory []models.PreferenceHistory `json:"preference_history"`
+	Sessions          []models.Session           `json:"sessions"`
+	APITokens         []models.APIToken          `json:"api_tokens"`
+	Notes             []string                   `json:"notes"`
+}
+
+// DataExportJob adalah status ekspor yang dibuat di background
+type DataExportJob struct {
+	models.DataExport
+	DownloadURL string `json:"download_url,omitempty"` // hanya terisi di respons yang membuat tautan unduh
+}
+
+// dataExportNotes menjelaskan data yang tidak disertakan karena memang tidak disimpan
+var dataExportNotes = []string{
+	"Assistant conversations are processed in real time and are not stored by the service, so none are included.",
+	"Password hashes, two-factor secrets, recovery codes and token secrets are never exported.",
+}
+
+// DataExportSyncMaxRecords mengembalikan jumlah catatan maksimum yang masih diekspor langsung di request;
+// akun yang lebih besar diekspor di background
+func DataExportSyncMaxRecords() int {
+	return config.GetEnvInt("DATA_EXPORT_SYNC_MAX_RECORDS", 500)
+}
+
+// DataExportTimeout mengembalikan batas waktu ekspor background (default 30 menit). Ekspor pending yang
+// lebih lama dari ini dianggap terhenti (misalnya karena server restart) dan ditandai gagal.
+func DataExportTimeout() time.Duration {
+	return config.GetEnvDuration("DATA_EXPORT_TIMEOUT", 30*time.Minute)
+}
+
+// DataExportTTL mengembalikan masa simpan hasil ekspor background beserta tautan unduhnya (default 24 jam)
+func DataExportTTL() time.Duration {
+	return config.GetEnvDuration("DATA_EXPORT_TTL", 24*time.Hour)
+}
+
+// BuildDataExport menyusun bundle ekspor data pribadi pengguna
+func BuildDataExport(userID uint) (*DataExportBundle, error) {
+	bundle := &DataExportBundle{
+		FormatVersion:     DataExportFormatVersion,
+		ExportedAt:        time.Now(),
+		PreferenceHistory: []models.PreferenceHistory{},
+		Sessions:          []models.Session{},
+		APITokens:         []models.APIToken{},
+		Notes:             dataExportNotes,
+	}
+
+	err := config.DB.
+		Preload("Preferences.Values").
+		Preload("Identities").
+		Preload("Passkeys").
+		First(&bundle.User, userID).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := config.DB.Where("user_id = ?", userID).Order("version, id").Find(&bundle.PreferenceHistory).Error; err != nil {
+		return nil, err
+	}
+	if err := config.DB.Where("user_id = ?", userID).Order("created_at").Find(&bundle.Sessions).Error; err != nil {
+		return nil, err
+	}
+	if err := config.DB.Where("user_id = ?", userID).Order("created_at").Find(&bundle.APITokens).Error; err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// DataExportNeedsBackground menentukan apakah akun cukup besar untuk diekspor di background
+func DataExportNeedsBackground(userID uint) (bool, error) {
+	total := int64(0)
+	for _, model := range []interface{}{
+		&models.PreferenceHistory{},
+		&models.Session{},
+		&models.APIToken{},
+		&models.ExternalIdentity{},
+		&models.WebAuthnCredential{},
+	} {
+		var count int64
+		if err := config.DB.Model(model).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return false, err
+		}
+		total += count
+	}
+	return total > int64(DataExportSyncMaxRecords()), nil
+}
+
+// StartDataExport membuat ekspor di background. Jika pengguna masih punya ekspor yang sedang diproses,
+// ekspor itu yang dikembalikan.
+func StartDataExport(userID uint) (*DataExportJob, error) {
+	now := time.Now()
+
+	// Ekspor pending yang sudah melewati batas waktu tidak akan selesai dan tidak menghalangi ekspor baru
+	var pending models.DataExport
+	result := config.DB.Omit("data").
+		Where("user_id = ? AND status = ? AND created_at > ?", userID, models.DataExportPending, now.Add(-DataExportTimeout())).
+		First(&pending)
+	if result.Error == nil {
+		return &DataExportJob{DataExport: pending}, nil
+	}
+	if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, result.Error
+	}
+
+	id, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	export := models.DataExport{
+		ID:        id,
+		UserID:    userID,
+		Status:    models.DataExportPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(DataExportTTL()),
+	}
+	if err := config.DB.Create(&export).Error; err != nil {
+		return nil, err
+	}
+
+	go runDataExport(export.ID, userID)
+	return &DataExportJob{DataExport: export}, nil
+}
+
+// GetDataExport mengambil status ekspor milik pengguna
+func GetDataExport(userID uint, exportID string) (*DataExportJob, error) {
+	var export models.DataExport
+	result := config.DB.Omit("data").
+		Where("id = ? AND user_id = ? AND expires_at > ?", exportID, userID, time.Now()).
+		First(&export)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrDataExportNotFound
+		}
+		return nil, result.Error
+	}
+	return &DataExportJob{DataExport: export}, nil
+}
+
+// ClaimDataExportLink membuat tautan unduh pertama untuk ekspor yang sudah selesai. Token mentah hanya
+// dikembalikan sekali; jika tautan sudah pernah dibuat, status dikembalikan tanpa download_url.
+func ClaimDataExportLink(userID uint, exportID string) (*DataExportJob, error) {
+	return issueDataExportLink(userID, exportID, true)
+}
+
+// CreateDataExportLink membuat tautan unduh baru untuk ekspor yang sudah selesai. Tautan lama tidak berlaku lagi.
+func CreateDataExportLink(userID uint, exportID string) (*DataExportJob, error) {
+	return issueDataExportLink(userID, exportID, false)
+}
+
+// issueDataExportLink menyimpan hash token unduh baru; jika firstOnly, hash hanya diisi bila belum ada
+func issueDataExportLink(userID uint, exportID string, firstOnly bool) (*DataExportJob, error) {
+	job, err := GetDataExport(userID, exportID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != models.DataExportReady {
+		return nil, ErrDataExportNotReady
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	db := config.DB.Model(&models.DataExport{}).Where("id = ?", job.ID)
+	if firstOnly {
+		// Syarat ini membuat hanya satu permintaan status yang mendapat tautan, meskipun datang bersamaan
+		db = db.Where("download_token_hash = ''")
+	}
+	result := db.Update("download_token_hash", utils.HashToken(token))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 1 {
+		job.DownloadURL = "/api/exports/download?token=" + token
+	}
+	return job, nil
+}
+
+// DownloadDataExport mengambil isi ekspor berdasarkan token dari tautan unduh
+func DownloadDataExport(token string) (*models.DataExport, error) {
+	if token == "" {
+		return nil, ErrDataExportNotFound
+	}
+
+	var export models.DataExport
+	result := config.DB.
+		Where("download_token_hash = ? AND status = ? AND expires_at > ?", utils.HashToken(token), models.DataExportReady, time.Now()).
+		First(&export)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrDataExportNotFound
+		}
+		return nil, result.Error
+	}
+	return &export, nil
+}
+
+// runDataExport menyusun bundle di background dan menyimpan hasilnya
+func runDataExport(exportID string, userID uint) {
+	updates := map[string]interface{}{"completed_at": time.Now()}
+
+	bundle, err := BuildDataExport(userID)
+	if err == nil {
+		var data []byte
+		data, err = json.Marshal(bundle)
+		updates["data"] = data
+	}
+	if err != nil {
+		log.Printf("Failed to build data export %s: %v", exportID, err)
+		updates["status"] = models.DataExportFailed
+		updates["error"] = "failed to build export"
+		delete(updates, "data")
+	} else {
+		updates["status"] = models.DataExportReady
+	}
+
+	// Syarat status pending mencegah hasil menimpa ekspor yang sudah ditandai gagal karena melewati batas waktu
+	err = config.DB.Model(&models.DataExport{}).
+		Where("id = ? AND status = ?", exportID, models.DataExportPending).
+		Updates(updates).Error
+	if err != nil {
+		log.Printf("Failed to save data export %s: %v", exportID, err)
+	}
+}
+
+// PruneDataExports menghapus ekspor yang sudah kedaluwarsa dan menandai ekspor pending yang melewati
+// DataExportTimeout sebagai gagal
+func PruneDataExports() error {
+	now := time.Now()
+	if err := config.DB.Where("expires_at < ?", now).Delete(&models.DataExport{}).Error; err != nil {
+		return err
+	}
+	return config.DB.Model(&models.DataExport{}).
+		Where("status = ? AND created_at < ?", models.DataExportPending, now.Add(-DataExportTimeout())).
+		Updates(map[string]interface{}{
+			"status":       models.DataExportFailed,
+			"error":        "export timed out",
+			"completed_at": now,
+		}).Error
+}
+
+// StartDataExportJanitor menjalankan PruneDataExports secara berkala di background
+func StartDataExportJanitor(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := PruneDataExports(); err != nil {
+				log.Printf("Failed to prune data exports: %v", err)
+			}
+		}
+	}()
+}
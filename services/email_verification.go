@@ -0,0 +1,122 @@
+// services/email_verification.go
+package services
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"main/config"
+	"main/models"
+	"main/utils"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidVerificationToken dikembalikan jika tautan verifikasi tidak valid, kedaluwarsa, atau untuk email lama
+var ErrInvalidVerificationToken = errors.New("invalid or expired verification link")
+
+// EmailVerificationTTL mengembalikan masa berlaku tautan verifikasi email (default 48 jam)
+func EmailVerificationTTL() time.Duration {
+	return config.GetEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour)
+}
+
+// EmailVerificationRequired menentukan apakah akun yang belum terverifikasi dibatasi aksesnya
+func EmailVerificationRequired() bool {
+	return config.GetEnvBool("REQUIRE_EMAIL_VERIFICATION", false)
+}
+
+// defaultUnverifiedAllowedRoutes adalah rute yang tetap bisa dipakai akun yang belum terverifikasi:
+// logout, kirim ulang tautan verifikasi, lihat/hapus akun dan perbaiki alamat email
+var defaultUnverifiedAllowedRoutes = []string{
+	"/api/auth/logout",
+	"/api/auth/logout-all",
+	"/api/auth/verify-email/resend",
+	"/api/user",
+	"/api/user/email",
+}
+
+// UnverifiedAllowedRoute memeriksa apakah path boleh diakses oleh akun yang belum terverifikasi.
+// Daftar path diatur lewat UNVERIFIED_ALLOWED_ROUTES (dipisahkan koma) dan dicocokkan persis,
+// sehingga "/api/user" tidak ikut membuka /api/user/tokens dan rute turunan lainnya.
+func UnverifiedAllowedRoute(path string) bool {
+	for _, route := range config.GetEnvList("UNVERIFIED_ALLOWED_ROUTES", defaultUnverifiedAllowedRoutes) {
+		if path == route {
+			return true
+		}
+	}
+	return false
+}
+
+// SendVerificationEmail mengirim tautan verifikasi bertanda tangan ke email pengguna
+func SendVerificationEmail(user models.User) error {
+	token, err := utils.GeneratePurposeToken(utils.PurposeEmailVerification, user.ID, user.Email, EmailVerificationTTL())
+	if err != nil {
+		return err
+	}
+
+	SendMailAsync(MailMessage{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: "Hi " + user.Username + ",\n\n" +
+			"Please confirm your email address by opening the link below. The link expires in " + EmailVerificationTTL().String() + ".\n\n" +
+			AppURL("/verify-email?token="+url.QueryEscape(token)) + "\n\n" +
+			"If you did not create an account, you can ignore this email.",
+	})
+	return nil
+}
+
+// VerifyEmail menandai email pengguna sebagai terverifikasi berdasarkan token dari tautan verifikasi
+func VerifyEmail(token string) (*models.User, error) {
+	claims, err := utils.ValidatePurposeToken(token, utils.PurposeEmailVerification)
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, claims.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, err
+	}
+
+	// Tautan ke email tertunda menyelesaikan perubahan email dari ChangeEmail
+	if user.PendingEmail != "" && user.PendingEmail == claims.Email {
+		if err := confirmPendingEmail(&user); err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+
+	// Tautan hanya berlaku untuk alamat email yang dikirimi tautan tersebut
+	if user.Email != claims.Email {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	if user.EmailVerified {
+		return &user, nil
+	}
+
+	now := time.Now()
+	err = config.DB.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"email_verified":    true,
+		"email_verified_at": now,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	user.EmailVerified = true
+	user.EmailVerifiedAt = &now
+	return &user, nil
+}
+
+// IsEmailVerified memeriksa status verifikasi email pengguna langsung dari database
+func IsEmailVerified(userID uint) (bool, error) {
+	var user models.User
+	if err := config.DB.Select("id", "email_verified").First(&user, userID).Error; err != nil {
+		return false, err
+	}
+	return user.EmailVerified, nil
+}
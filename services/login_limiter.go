@@ -0,0 +1,328 @@
+// services/login_limiter.go
+package services
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"main/config"
+	"main/models"
+	"main/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RateLimitError dikembalikan jika percobaan ditolak karena backoff atau penguncian
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return "too many failed attempts, try again later"
+}
+
+// AttemptStore menyimpan status login gagal per kunci. Implementasi bisa di memori
+// (satu instance) atau di database (beberapa instance berbagi status yang sama).
+type AttemptStore interface {
+	// Update menjalankan fn terhadap status kunci (kosong jika belum ada) dan menyimpan hasilnya secara atomik,
+	// sehingga kegagalan bersamaan di beberapa instance tidak saling menimpa hitungan
+	Update(key string, fn func(attempt *models.LoginAttempt)) (*models.LoginAttempt, error)
+	Delete(key string) error
+}
+
+// MemoryAttemptStore menyimpan status login gagal di memori proses
+type MemoryAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]models.LoginAttempt
+}
+
+// NewMemoryAttemptStore membuat AttemptStore di memori
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{attempts: make(map[string]models.LoginAttempt)}
+}
+
+// Update mengubah status percobaan di bawah mutex dan membuang entri yang sudah lama tidak aktif
+func (s *MemoryAttemptStore) Update(key string, fn func(attempt *models.LoginAttempt)) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		attempt = models.LoginAttempt{Key: key}
+	}
+	fn(&attempt)
+	s.attempts[key] = attempt
+
+	// Cegah map tumbuh tanpa batas saat banyak IP berbeda mencoba login
+	if len(s.attempts) > 10000 {
+		cutoff := time.Now().Add(-loginAttemptWindow())
+		for key, existing := range s.attempts {
+			if existing.LastFailureAt.Before(cutoff) && (existing.LockedUntil == nil || existing.LockedUntil.Before(time.Now())) {
+				delete(s.attempts, key)
+			}
+		}
+	}
+	return &attempt, nil
+}
+
+// Delete menghapus status percobaan untuk kunci
+func (s *MemoryAttemptStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+// DatabaseAttemptStore menyimpan status login gagal di tabel login_attempts
+type DatabaseAttemptStore struct{}
+
+// Update mengubah status percobaan di dalam transaksi dengan baris terkunci (SELECT ... FOR UPDATE)
+func (DatabaseAttemptStore) Update(key string, fn func(attempt *models.LoginAttempt)) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// FOR UPDATE tidak mengunci baris yang belum ada, jadi buat baris kosong lebih dulu
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.LoginAttempt{Key: key}).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&attempt).Error; err != nil {
+			return err
+		}
+		fn(&attempt)
+		return tx.Save(&attempt).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// Delete menghapus status percobaan untuk kunci
+func (DatabaseAttemptStore) Delete(key string) error {
+	return config.DB.Where("key = ?", key).Delete(&models.LoginAttempt{}).Error
+}
+
+// LoginLimiter menerapkan backoff eksponensial dan penguncian sementara setelah login gagal berulang
+type LoginLimiter struct {
+	store AttemptStore
+}
+
+var (
+	limiterOnce sync.Once
+	limiter     *LoginLimiter
+)
+
+// DefaultLoginLimiter mengembalikan limiter dengan store sesuai LOGIN_ATTEMPT_STORE (memory atau database)
+func DefaultLoginLimiter() *LoginLimiter {
+	limiterOnce.Do(func() {
+		var store AttemptStore = NewMemoryAttemptStore()
+		if config.GetEnv("LOGIN_ATTEMPT_STORE", "memory") == "database" {
+			store = DatabaseAttemptStore{}
+		}
+		limiter = NewLoginLimiter(store)
+	})
+	return limiter
+}
+
+// NewLoginLimiter membuat limiter dengan AttemptStore tertentu
+func NewLoginLimiter(store AttemptStore) *LoginLimiter {
+	return &LoginLimiter{store: store}
+}
+
+// UsernameKey membuat kunci limiter untuk username (tidak peka huruf besar/kecil)
+func UsernameKey(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+// LoginKey membuat kunci limiter per akun untuk identifier login. Akun yang ditemukan memakai username
+// kanonisnya sehingga login lewat username maupun email berbagi satu hitungan; identifier yang tidak cocok
+// dengan akun mana pun memakai kuncinya sendiri.
+func LoginKey(identifier string, user *models.User) string {
+	if user != nil {
+		return UsernameKey(utils.CanonicalIdentifier(user.Username))
+	}
+	return UsernameKey(utils.CanonicalIdentifier(identifier))
+}
+
+// MFAKey membuat kunci limiter untuk challenge 2FA milik pengguna
+func MFAKey(userID uint) string {
+	return "mfa:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// VerifyEmailKey membuat kunci limiter untuk pengiriman ulang email verifikasi milik pengguna
+func VerifyEmailKey(userID uint) string {
+	return "verify:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// IPKey membuat kunci limiter untuk alamat IP
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Attempt mencatat satu percobaan untuk setiap kunci secara atomik sebelum kredensial diperiksa, sehingga
+// permintaan yang datang bersamaan tidak bisa melewati ambang penguncian. Mengembalikan waktu tunggu jika
+// percobaan ditolak (selama backoff/penguncian, atau jika hitungan sudah melewati ambang); percobaan yang
+// ditolak tidak dihitung. Setelah kredensial diperiksa, panggil Failed, Release atau Succeed.
+func (l *LoginLimiter) Attempt(keys ...string) (time.Duration, error) {
+	now := time.Now()
+	var wait time.Duration
+	var counted []string
+
+	for _, key := range keys {
+		var keyWait time.Duration
+		_, err := l.store.Update(key, func(attempt *models.LoginAttempt) {
+			resetStaleAttempt(attempt, now)
+
+			if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+				keyWait = attempt.LockedUntil.Sub(now)
+				return
+			}
+			if attempt.NextAllowedAt.After(now) {
+				keyWait = attempt.NextAllowedAt.Sub(now)
+				return
+			}
+
+			attempt.Failures++
+			attempt.LastFailureAt = now
+			attempt.UpdatedAt = now
+
+			// Percobaan bersamaan yang melewati ambang langsung mengunci kunci ini
+			if attempt.Failures > lockoutThreshold(attempt.Key) {
+				attempt.Failures--
+				lockedUntil := now.Add(loginLockoutDuration())
+				attempt.LockedUntil = &lockedUntil
+				attempt.NextAllowedAt = lockedUntil
+				keyWait = loginLockoutDuration()
+			}
+		})
+		if err != nil {
+			return 0, err
+		}
+		if keyWait > 0 {
+			wait = maxDuration(wait, keyWait)
+		} else {
+			counted = append(counted, key)
+		}
+	}
+
+	// Percobaan yang ditolak tidak dihitung pada kunci lain
+	if wait > 0 {
+		if err := l.Release(counted...); err != nil {
+			return 0, err
+		}
+	}
+	return wait, nil
+}
+
+// Failed menerapkan backoff dan penguncian untuk percobaan yang sudah dicatat Attempt dan ternyata gagal
+func (l *LoginLimiter) Failed(keys ...string) (time.Duration, error) {
+	now := time.Now()
+	var wait time.Duration
+
+	for _, key := range keys {
+		attempt, err := l.store.Update(key, func(attempt *models.LoginAttempt) {
+			applyBackoff(attempt, now)
+		})
+		if err != nil {
+			return 0, err
+		}
+		wait = maxDuration(wait, attempt.NextAllowedAt.Sub(now))
+	}
+	return wait, nil
+}
+
+// Release membatalkan hitungan dari Attempt untuk percobaan yang tidak gagal (mis. kunci IP setelah login berhasil)
+func (l *LoginLimiter) Release(keys ...string) error {
+	for _, key := range keys {
+		_, err := l.store.Update(key, func(attempt *models.LoginAttempt) {
+			if attempt.Failures > 0 {
+				attempt.Failures--
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resetStaleAttempt mengosongkan hitungan lama di luar jendela waktu yang tidak sedang terkunci
+func resetStaleAttempt(attempt *models.LoginAttempt, now time.Time) {
+	if now.Sub(attempt.LastFailureAt) > loginAttemptWindow() && (attempt.LockedUntil == nil || attempt.LockedUntil.Before(now)) {
+		*attempt = models.LoginAttempt{Key: attempt.Key}
+	}
+}
+
+// applyBackoff menghitung waktu tunggu berikutnya dari jumlah kegagalan dan mengunci kunci setelah ambang batas
+func applyBackoff(attempt *models.LoginAttempt, now time.Time) {
+	failures := attempt.Failures
+	if failures < 1 {
+		failures = 1
+	}
+
+	// Backoff eksponensial: base, 2*base, 4*base, ... dibatasi LOGIN_BACKOFF_MAX
+	backoff := time.Duration(float64(loginBackoffBase()) * math.Pow(2, float64(failures-1)))
+	if backoff > loginBackoffMax() || backoff <= 0 {
+		backoff = loginBackoffMax()
+	}
+	attempt.NextAllowedAt = now.Add(backoff)
+	attempt.UpdatedAt = now
+
+	// Kunci sementara setelah melewati ambang batas
+	if failures >= lockoutThreshold(attempt.Key) {
+		lockedUntil := now.Add(loginLockoutDuration())
+		attempt.LockedUntil = &lockedUntil
+		attempt.NextAllowedAt = lockedUntil
+	}
+}
+
+// Succeed menghapus riwayat gagal untuk kunci setelah login berhasil
+func (l *LoginLimiter) Succeed(keys ...string) error {
+	for _, key := range keys {
+		if err := l.store.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unlock membuka penguncian untuk kunci tertentu (operasi admin)
+func (l *LoginLimiter) Unlock(keys ...string) error {
+	return l.Succeed(keys...)
+}
+
+// lockoutThreshold mengembalikan jumlah kegagalan sebelum kunci dikunci; ambang IP lebih longgar
+// karena banyak pengguna bisa berbagi satu IP (NAT, kantor)
+func lockoutThreshold(key string) int {
+	if strings.HasPrefix(key, "ip:") {
+		return config.GetEnvInt("LOGIN_IP_LOCKOUT_THRESHOLD", 20)
+	}
+	return config.GetEnvInt("LOGIN_LOCKOUT_THRESHOLD", 5)
+}
+
+func loginLockoutDuration() time.Duration {
+	return config.GetEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+}
+
+func loginBackoffBase() time.Duration {
+	return config.GetEnvDuration("LOGIN_BACKOFF_BASE", time.Second)
+}
+
+func loginBackoffMax() time.Duration {
+	return config.GetEnvDuration("LOGIN_BACKOFF_MAX", 5*time.Minute)
+}
+
+func loginAttemptWindow() time.Duration {
+	return config.GetEnvDuration("LOGIN_ATTEMPT_WINDOW", 15*time.Minute)
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
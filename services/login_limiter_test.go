@@ -0,0 +1,120 @@
+// services/login_limiter_test.go
+package services
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestLoginLimiterAttemptBurst(t *testing.T) {
+	t.Setenv("LOGIN_LOCKOUT_THRESHOLD", "5")
+	limiter := NewLoginLimiter(NewMemoryAttemptStore())
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wait, err := limiter.Attempt("user:alice")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if wait == 0 {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed > 5 {
+		t.Errorf("concurrent burst let %d attempts through, want at most 5", allowed)
+	}
+}
+
+func TestLoginLimiterSequence(t *testing.T) {
+	t.Setenv("LOGIN_LOCKOUT_THRESHOLD", "3")
+	t.Setenv("LOGIN_IP_LOCKOUT_THRESHOLD", "3")
+	t.Setenv("LOGIN_BACKOFF_BASE", "0s")
+	t.Setenv("LOGIN_BACKOFF_MAX", "0s")
+
+	// failN mencatat n percobaan gagal berturut-turut
+	failN := func(l *LoginLimiter, key string, n int) error {
+		for i := 0; i < n; i++ {
+			if _, err := l.Attempt(key); err != nil {
+				return err
+			}
+			if _, err := l.Failed(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name     string
+		key      string
+		run      func(l *LoginLimiter, key string) error
+		wantWait bool
+	}{
+		{
+			name: "failures below the threshold are allowed",
+			key:  "user:bob",
+			run:  func(l *LoginLimiter, key string) error { return failN(l, key, 2) },
+		},
+		{
+			name:     "reaching the threshold locks the key",
+			key:      "user:bob",
+			run:      func(l *LoginLimiter, key string) error { return failN(l, key, 3) },
+			wantWait: true,
+		},
+		{
+			name: "released attempts are not counted",
+			key:  "ip:192.0.2.1",
+			run: func(l *LoginLimiter, key string) error {
+				for i := 0; i < 5; i++ {
+					if _, err := l.Attempt(key); err != nil {
+						return err
+					}
+					if err := l.Release(key); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			name: "success clears earlier failures",
+			key:  "user:bob",
+			run: func(l *LoginLimiter, key string) error {
+				if err := failN(l, key, 2); err != nil {
+					return err
+				}
+				return l.Succeed(key)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewLoginLimiter(NewMemoryAttemptStore())
+			if err := tt.run(limiter, tt.key); err != nil {
+				t.Fatal(err)
+			}
+
+			wait, err := limiter.Attempt(tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (wait > 0) != tt.wantWait {
+				t.Errorf("next Attempt wait = %v, want blocked %v", wait, tt.wantWait)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+// services/magic_link.go
+package services
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"main/config"
+	"main/models"
+	"main/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidMagicLink dikembalikan jika tautan login tidak valid, kedaluwarsa, atau sudah dipakai
+var ErrInvalidMagicLink = errors.New("invalid or expired login link")
+
+// MagicLinkTTL mengembalikan masa berlaku tautan login (default 15 menit)
+func MagicLinkTTL() time.Duration {
+	return config.GetEnvDuration("MAGIC_LINK_TTL", 15*time.Minute)
+}
+
+// MagicLinkEnabled menentukan apakah login dengan tautan email tersedia (default aktif)
+func MagicLinkEnabled() bool {
+	return config.GetEnvBool("MAGIC_LINK_ENABLED", true)
+}
+
+// MagicLinkAutoRegister menentukan apakah tautan login juga dikirim ke email yang belum terdaftar
+// (akun dibuat saat tautan ditukar)
+func MagicLinkAutoRegister() bool {
+	return config.GetEnvBool("MAGIC_LINK_AUTO_REGISTER", false)
+}
+
+// RequestMagicLink mengirim tautan login sekali pakai ke email. Jika email tidak terdaftar (dan pendaftaran
+// otomatis mati), akun dinonaktifkan, atau akun menunggu penghapusan permanen, fungsi ini tidak melakukan
+// apa-apa agar keberadaan akun tidak bocor.
+func RequestMagicLink(email string) error {
+	email = strings.TrimSpace(email)
+
+	var user models.User
+	result := config.DB.Unscoped().Where("email_canonical = ?", utils.CanonicalIdentifier(email)).First(&user)
+	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return result.Error
+	}
+
+	var userID uint
+	greeting := "Hi,"
+	switch {
+	case result.Error == nil && (user.Disabled || user.DeletedAt.Valid):
+		return nil
+	case result.Error == nil:
+		userID = user.ID
+		email = user.Email // tautan terikat ke email yang tersimpan, bukan ejaan yang diketik pengguna
+		greeting = "Hi " + user.Username + ","
+	case !MagicLinkAutoRegister():
+		return nil
+	}
+
+	// Token untuk email yang belum terdaftar memakai user_id 0; akun dibuat saat tautan ditukar
+	token, err := utils.GeneratePurposeToken(utils.PurposeMagicLink, userID, email, MagicLinkTTL())
+	if err != nil {
+		return err
+	}
+
+	SendMailAsync(MailMessage{
+		To:      email,
+		Subject: "Your sign-in link",
+		Body: greeting + "\n\n" +
+			"Use the link below to sign in. The link expires in " + MagicLinkTTL().String() + " and can only be used once.\n\n" +
+			AppURL("/magic-link?token="+url.QueryEscape(token)) + "\n\n" +
+			"If you did not request this link, you can ignore this email.",
+	})
+	return nil
+}
+
+// ConsumeMagicLink menukar tautan login dengan pengguna yang bersangkutan. Tautan hanya bisa dipakai sekali;
+// untuk email yang belum terdaftar, akun baru dibuat dengan preferensi default (jika diizinkan).
+// Membuka tautan membuktikan kepemilikan email, sehingga email ditandai terverifikasi.
+func ConsumeMagicLink(token string) (*models.User, error) {
+	claims, err := utils.ValidatePurposeToken(token, utils.PurposeMagicLink)
+	if err != nil || claims.ID == "" || claims.ExpiresAt == nil {
+		return nil, ErrInvalidMagicLink
+	}
+
+	var user models.User
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Catat jti; jika sudah ada berarti tautan sudah pernah dipakai
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.UsedMagicLink{
+			JTI:       claims.ID,
+			ExpiresAt: claims.ExpiresAt.Time,
+			UsedAt:    time.Now(),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidMagicLink
+		}
+
+		if claims.UserID == 0 {
+			return registerFromMagicLink(tx, claims.Email, &user)
+		}
+
+		if err := tx.First(&user, claims.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidMagicLink
+			}
+			return err
+		}
+		// Tautan hanya berlaku untuk alamat email yang dikirimi tautan tersebut
+		if user.Email != claims.Email {
+			return ErrInvalidMagicLink
+		}
+		return markEmailVerified(tx, &user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// registerFromMagicLink membuat akun untuk email yang belum terdaftar saat tautan login ditukar
+func registerFromMagicLink(tx *gorm.DB, email string, user *models.User) error {
+	if !MagicLinkAutoRegister() {
+		return ErrInvalidMagicLink
+	}
+
+	// Email bisa saja sudah didaftarkan sejak tautan dikirim; login ke akun itu
+	result := tx.Unscoped().Where("email_canonical = ?", utils.CanonicalIdentifier(email)).First(user)
+	if result.Error == nil {
+		if user.DeletedAt.Valid {
+			return ErrInvalidMagicLink
+		}
+		return markEmailVerified(tx, user)
+	}
+	if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return result.Error
+	}
+
+	username, err := availableUsername(tx, "", email)
+	if err != nil {
+		return err
+	}
+
+	// Akun dari tautan login tidak memiliki password; pengguna bisa membuatnya lewat reset password
+	now := time.Now()
+	*user = models.User{
+		Username:        username,
+		Email:           email,
+		EmailVerified:   true,
+		EmailVerifiedAt: &now,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	return createUserWithPreferences(tx, user)
+}
+
+// PruneUsedMagicLinks menghapus catatan pemakaian tautan login yang sudah kedaluwarsa. Tautan seperti itu
+// sudah ditolak oleh validasi token, sehingga catatannya tidak diperlukan lagi.
+func PruneUsedMagicLinks() error {
+	return config.DB.Where("expires_at < ?", time.Now()).Delete(&models.UsedMagicLink{}).Error
+}
+
+// StartMagicLinkJanitor menjalankan PruneUsedMagicLinks secara berkala di background
+func StartMagicLinkJanitor(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := PruneUsedMagicLinks(); err != nil {
+				log.Printf("Failed to prune used magic links: %v", err)
+			}
+		}
+	}()
+}
+
+// markEmailVerified menandai email pengguna terverifikasi jika belum
+func markEmailVerified(tx *gorm.DB, user *models.User) error {
+	if user.EmailVerified {
+		return nil
+	}
+
+	now := time.Now()
+	if err := tx.Model(user).Updates(map[string]interface{}{
+		"email_verified":    true,
+		"email_verified_at": now,
+	}).Error; err != nil {
+		return err
+	}
+	user.EmailVerified = true
+	user.EmailVerifiedAt = &now
+	return nil
+}
@@ -0,0 +1,143 @@
+// services/mailer.go
+package services
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"main/config"
+)
+
+// MailMessage merupakan email yang akan dikirim
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer adalah antarmuka pengirim email sehingga implementasinya bisa diganti (SMTP, file/log, dll)
+type Mailer interface {
+	Send(msg MailMessage) error
+}
+
+// SMTPMailer mengirim email melalui server SMTP
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send mengirim email melalui SMTP (STARTTLS dipakai otomatis jika didukung server)
+func (m *SMTPMailer) Send(msg MailMessage) error {
+	// Field header tidak boleh mengandung CR/LF agar tidak bisa menyisipkan header atau penerima lain
+	if err := checkHeaderValues(m.From, msg.To, msg.Subject); err != nil {
+		return err
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address: %w", err)
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	headers := []string{
+		"From: " + m.From,
+		"To: " + to.String(),
+		"Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+	body := strings.Join(headers, "\r\n") + "\r\n\r\n" + msg.Body
+
+	return smtp.SendMail(net.JoinHostPort(m.Host, m.Port), auth, m.From, []string{to.Address}, []byte(body))
+}
+
+// checkHeaderValues menolak nilai header yang mengandung CR atau LF
+func checkHeaderValues(values ...string) error {
+	for _, value := range values {
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("mail header value must not contain line breaks")
+		}
+	}
+	return nil
+}
+
+// LogMailer menulis email ke file (atau ke log jika Path kosong) untuk pengembangan lokal dan pengujian
+type LogMailer struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// Send menulis email ke file atau log alih-alih mengirimkannya
+func (m *LogMailer) Send(msg MailMessage) error {
+	entry := fmt.Sprintf("[%s] To: %s\nSubject: %s\n\n%s\n---\n", time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+
+	if m.Path == "" {
+		log.Print("Mail (not sent):\n" + entry)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := os.OpenFile(m.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(entry)
+	return err
+}
+
+var (
+	mailerOnce sync.Once
+	mailer     Mailer
+)
+
+// DefaultMailer mengembalikan mailer sesuai konfigurasi MAIL_DRIVER (smtp atau log)
+func DefaultMailer() Mailer {
+	mailerOnce.Do(func() {
+		switch config.GetEnv("MAIL_DRIVER", "log") {
+		case "smtp":
+			mailer = &SMTPMailer{
+				Host:     config.GetEnv("SMTP_HOST", "localhost"),
+				Port:     config.GetEnv("SMTP_PORT", "587"),
+				Username: config.GetEnv("SMTP_USERNAME", ""),
+				Password: config.GetEnv("SMTP_PASSWORD", ""),
+				From:     config.GetEnv("MAIL_FROM", "no-reply@localhost"),
+			}
+		default:
+			mailer = &LogMailer{Path: config.GetEnv("MAIL_LOG_FILE", "")}
+		}
+	})
+	return mailer
+}
+
+// SendMailAsync mengirim email di background agar waktu respons tidak membocorkan hasil pencarian akun
+func SendMailAsync(msg MailMessage) {
+	go func() {
+		if err := DefaultMailer().Send(msg); err != nil {
+			log.Printf("Failed to send mail to %s: %v", msg.To, err)
+		}
+	}()
+}
+
+// AppURL membangun URL frontend dari APP_BASE_URL dan path yang diberikan
+func AppURL(path string) string {
+	return strings.TrimRight(config.GetEnv("APP_BASE_URL", "http://localhost:3000"), "/") + path
+}
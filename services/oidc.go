@@ -0,0 +1,692 @@
+// services/oidc.go
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"main/config"
+	"main/models"
+	"main/utils"
+
+	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrUnknownOIDCProvider dikembalikan jika nama penyedia tidak ada di OIDC_PROVIDERS
+	ErrUnknownOIDCProvider = errors.New("unknown identity provider")
+	// ErrInvalidOIDCState dikembalikan jika state tidak dikenal, sudah dipakai, atau kedaluwarsa
+	ErrInvalidOIDCState = errors.New("invalid or expired login state")
+	// ErrOIDCExchangeFailed dikembalikan jika penukaran kode atau pengambilan metadata penyedia gagal
+	ErrOIDCExchangeFailed = errors.New("identity provider request failed")
+	// ErrInvalidIDToken dikembalikan jika ID token tidak lolos validasi
+	ErrInvalidIDToken = errors.New("invalid id token")
+	// ErrOIDCEmailRequired dikembalikan jika penyedia tidak memberikan email untuk akun baru
+	ErrOIDCEmailRequired = errors.New("identity provider did not return an email address")
+	// ErrOIDCEmailInUse dikembalikan jika email identitas baru sudah dipakai akun lokal.
+	// Akun tidak pernah ditautkan otomatis berdasarkan email; pengguna harus login lalu menautkan identitasnya.
+	ErrOIDCEmailInUse = errors.New("an account with this email already exists; sign in and link this identity from your account settings")
+	// ErrOIDCRegistrationDisabled dikembalikan jika pembuatan akun otomatis dimatikan untuk penyedia
+	ErrOIDCRegistrationDisabled = errors.New("automatic account creation is disabled for this identity provider")
+	// ErrIdentityAlreadyLinked dikembalikan jika identitas eksternal sudah tertaut ke akun lain
+	ErrIdentityAlreadyLinked = errors.New("this identity is already linked to another account")
+	// ErrIdentityNotFound dikembalikan jika identitas yang diminta tidak ada atau bukan milik pengguna
+	ErrIdentityNotFound = errors.New("identity not found")
+	// ErrLastLoginMethod dikembalikan jika pengguna mencoba melepas satu-satunya cara login
+	ErrLastLoginMethod = errors.New("cannot remove the only sign-in method; set a password first")
+)
+
+// oidcSigningMethods adalah algoritma ID token yang diterima (tanpa HMAC dan "none")
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+var oidcHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// OIDCProvider adalah konfigurasi satu penyedia identitas OIDC.
+//
+// Penyedia didaftarkan lewat OIDC_PROVIDERS (dipisahkan koma), lalu setiap penyedia dikonfigurasi dengan
+// OIDC_<NAMA>_ISSUER, OIDC_<NAMA>_CLIENT_ID, OIDC_<NAMA>_CLIENT_SECRET, OIDC_<NAMA>_REDIRECT_URL,
+// OIDC_<NAMA>_SCOPES dan OIDC_<NAMA>_AUTO_REGISTER.
+type OIDCProvider struct {
+	Name         string   `json:"name"`
+	DisplayName  string   `json:"display_name"`
+	Issuer       string   `json:"-"`
+	ClientID     string   `json:"-"`
+	ClientSecret string   `json:"-"`
+	RedirectURL  string   `json:"-"`
+	Scopes       []string `json:"-"`
+	AutoRegister bool     `json:"-"`
+}
+
+// OIDCAuthStart berisi URL otorisasi yang harus dibuka pengguna untuk memulai login OIDC
+type OIDCAuthStart struct {
+	AuthorizationURL string    `json:"authorization_url"`
+	State            string    `json:"state"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+// OIDCAuthResult adalah hasil callback OIDC: login (dengan akun yang mungkin baru dibuat) atau penautan identitas
+type OIDCAuthResult struct {
+	User     models.User
+	Identity models.ExternalIdentity
+	Linked   bool // true jika alur ini menautkan identitas ke akun yang sudah login
+	Created  bool // true jika akun baru dibuat dari identitas ini
+}
+
+// oidcDiscovery adalah bagian dokumen /.well-known/openid-configuration yang dipakai
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcIDTokenClaims adalah klaim ID token yang dibaca dari penyedia
+type oidcIDTokenClaims struct {
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     oidcBool `json:"email_verified"`
+	PreferredUsername string   `json:"preferred_username"`
+	Name              string   `json:"name"`
+	AuthorizedParty   string   `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// oidcBool menerima email_verified sebagai boolean maupun string ("true"), karena beberapa penyedia mengirim string
+type oidcBool bool
+
+func (b *oidcBool) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case bool:
+		*b = oidcBool(v)
+	case string:
+		parsed, _ := strconv.ParseBool(v)
+		*b = oidcBool(parsed)
+	}
+	return nil
+}
+
+// OIDCProviders mengembalikan semua penyedia yang dikonfigurasi lengkap (issuer dan client ID terisi)
+func OIDCProviders() []OIDCProvider {
+	var providers []OIDCProvider
+	for _, name := range config.GetEnvList("OIDC_PROVIDERS", nil) {
+		if provider, err := GetOIDCProvider(name); err == nil {
+			providers = append(providers, *provider)
+		}
+	}
+	return providers
+}
+
+// GetOIDCProvider membaca konfigurasi penyedia berdasarkan namanya
+func GetOIDCProvider(name string) (*OIDCProvider, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	registered := false
+	for _, candidate := range config.GetEnvList("OIDC_PROVIDERS", nil) {
+		if strings.ToLower(candidate) == name {
+			registered = true
+			break
+		}
+	}
+	if !registered || name == "" {
+		return nil, ErrUnknownOIDCProvider
+	}
+
+	prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+	provider := &OIDCProvider{
+		Name:         name,
+		DisplayName:  config.GetEnv(prefix+"DISPLAY_NAME", name),
+		Issuer:       strings.TrimSuffix(config.GetEnv(prefix+"ISSUER", ""), "/"),
+		ClientID:     config.GetEnv(prefix+"CLIENT_ID", ""),
+		ClientSecret: config.GetEnv(prefix+"CLIENT_SECRET", ""),
+		RedirectURL:  config.GetEnv(prefix+"REDIRECT_URL", AppURL("/auth/oidc/callback")),
+		Scopes:       config.GetEnvList(prefix+"SCOPES", []string{"openid", "email", "profile"}),
+		AutoRegister: config.GetEnvBool(prefix+"AUTO_REGISTER", true),
+	}
+	if provider.Issuer == "" || provider.ClientID == "" {
+		return nil, ErrUnknownOIDCProvider
+	}
+	return provider, nil
+}
+
+// OIDCStateTTL mengembalikan masa berlaku state login OIDC (default 10 menit)
+func OIDCStateTTL() time.Duration {
+	return config.GetEnvDuration("OIDC_STATE_TTL", 10*time.Minute)
+}
+
+// StartOIDCAuth membuat state, PKCE verifier dan nonce baru lalu menyusun URL otorisasi penyedia.
+// linkUserID diisi jika identitas akan ditautkan ke akun yang sedang login, nil untuk login biasa.
+func StartOIDCAuth(providerName string, linkUserID *uint) (*OIDCAuthStart, error) {
+	provider, err := GetOIDCProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	discovery, err := provider.discovery()
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(OIDCStateTTL())
+	request := models.OIDCAuthRequest{
+		StateHash:    utils.HashToken(state),
+		Provider:     provider.Name,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		UserID:       linkUserID,
+		ExpiresAt:    expiresAt,
+		CreatedAt:    time.Now(),
+	}
+	if err := config.DB.Create(&request).Error; err != nil {
+		return nil, err
+	}
+
+	// Bersihkan state yang kedaluwarsa tanpa menghambat request
+	go config.DB.Where("expires_at < ?", time.Now()).Delete(&models.OIDCAuthRequest{})
+
+	challenge := sha256.Sum256([]byte(verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {provider.ClientID},
+		"redirect_uri":          {provider.RedirectURL},
+		"scope":                 {strings.Join(provider.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	authURL, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid authorization endpoint", ErrOIDCExchangeFailed)
+	}
+	query := authURL.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	authURL.RawQuery = query.Encode()
+
+	return &OIDCAuthStart{
+		AuthorizationURL: authURL.String(),
+		State:            state,
+		ExpiresAt:        expiresAt,
+	}, nil
+}
+
+// CancelOIDCAuth membuang state login OIDC, misalnya saat penyedia mengembalikan error
+func CancelOIDCAuth(state string) error {
+	return config.DB.Where("state_hash = ?", utils.HashToken(state)).Delete(&models.OIDCAuthRequest{}).Error
+}
+
+// CompleteOIDCAuth memproses callback penyedia: menukar kode (dengan PKCE verifier), memvalidasi ID token,
+// lalu menautkan identitas atau mencari/membuat akun untuk login
+func CompleteOIDCAuth(state, code string) (*OIDCAuthResult, error) {
+	// Ambil dan hapus state secara atomik agar tidak bisa dipakai ulang
+	var request models.OIDCAuthRequest
+	result := config.DB.Clauses(clause.Returning{}).
+		Where("state_hash = ?", utils.HashToken(state)).
+		Delete(&request)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || time.Now().After(request.ExpiresAt) {
+		return nil, ErrInvalidOIDCState
+	}
+
+	provider, err := GetOIDCProvider(request.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := provider.exchangeCode(code, request.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := provider.validateIDToken(idToken, request.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.UserID != nil {
+		return linkIdentity(*request.UserID, provider, claims)
+	}
+	return loginWithIdentity(provider, claims)
+}
+
+// ListIdentities menampilkan semua identitas eksternal yang tertaut ke pengguna
+func ListIdentities(userID uint) ([]models.ExternalIdentity, error) {
+	var identities []models.ExternalIdentity
+	err := config.DB.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error
+	return identities, err
+}
+
+// UnlinkIdentity melepas identitas eksternal dari akun. Identitas tidak bisa dilepas jika itu
+// satu-satunya cara login akun (tanpa password maupun passkey), karena pengguna tidak akan bisa login lagi.
+func UnlinkIdentity(userID, identityID uint) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var identity models.ExternalIdentity
+		result := tx.Where("id = ? AND user_id = ?", identityID, userID).First(&identity)
+		if result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return ErrIdentityNotFound
+			}
+			return result.Error
+		}
+
+		methods, err := countLoginMethods(tx, userID)
+		if err != nil {
+			return err
+		}
+		if methods <= 1 {
+			return ErrLastLoginMethod
+		}
+
+		return tx.Delete(&identity).Error
+	})
+}
+
+// linkIdentity menautkan identitas eksternal ke akun yang memulai alur penautan
+func linkIdentity(userID uint, provider *OIDCProvider, claims *oidcIDTokenClaims) (*OIDCAuthResult, error) {
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	var identity models.ExternalIdentity
+	result := config.DB.Where("provider = ? AND subject = ?", provider.Name, claims.Subject).First(&identity)
+	if result.Error == nil {
+		if identity.UserID != userID {
+			return nil, ErrIdentityAlreadyLinked
+		}
+		return &OIDCAuthResult{User: user, Identity: identity, Linked: true}, nil
+	}
+	if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, result.Error
+	}
+
+	identity = models.ExternalIdentity{
+		UserID:    userID,
+		Provider:  provider.Name,
+		Subject:   claims.Subject,
+		Email:     claims.Email,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := config.DB.Create(&identity).Error; err != nil {
+		return nil, err
+	}
+	return &OIDCAuthResult{User: user, Identity: identity, Linked: true}, nil
+}
+
+// loginWithIdentity mencari akun dari identitas eksternal, atau membuat akun baru jika diizinkan
+func loginWithIdentity(provider *OIDCProvider, claims *oidcIDTokenClaims) (*OIDCAuthResult, error) {
+	now := time.Now()
+
+	var identity models.ExternalIdentity
+	result := config.DB.Where("provider = ? AND subject = ?", provider.Name, claims.Subject).First(&identity)
+	if result.Error == nil {
+		var user models.User
+		if err := config.DB.First(&user, identity.UserID).Error; err != nil {
+			// Identitas akun yang dihapus tetap ada sampai akun dihapus permanen
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrAccountPendingDeletion
+			}
+			return nil, err
+		}
+
+		identity.Email = claims.Email
+		identity.LastLoginAt = &now
+		if err := config.DB.Model(&identity).Updates(map[string]interface{}{
+			"email":         claims.Email,
+			"last_login_at": now,
+		}).Error; err != nil {
+			return nil, err
+		}
+		return &OIDCAuthResult{User: user, Identity: identity}, nil
+	}
+	if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, result.Error
+	}
+
+	if !provider.AutoRegister {
+		return nil, ErrOIDCRegistrationDisabled
+	}
+	if claims.Email == "" {
+		return nil, ErrOIDCEmailRequired
+	}
+
+	// Jangan pernah menautkan otomatis berdasarkan email: email dari penyedia bisa saja milik orang lain
+	var count int64
+	if err := config.DB.Unscoped().Model(&models.User{}).Where("email_canonical = ?", utils.CanonicalIdentifier(claims.Email)).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrOIDCEmailInUse
+	}
+
+	var user models.User
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		username, err := availableUsername(tx, claims.PreferredUsername, claims.Email)
+		if err != nil {
+			return err
+		}
+
+		// Akun dari OIDC tidak memiliki password; pengguna bisa membuatnya lewat reset password
+		user = models.User{
+			Username:      username,
+			Email:         claims.Email,
+			EmailVerified: bool(claims.EmailVerified),
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if user.EmailVerified {
+			user.EmailVerifiedAt = &now
+		}
+		if err := createUserWithPreferences(tx, &user); err != nil {
+			return err
+		}
+
+		identity = models.ExternalIdentity{
+			UserID:      user.ID,
+			Provider:    provider.Name,
+			Subject:     claims.Subject,
+			Email:       claims.Email,
+			LastLoginAt: &now,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		return tx.Create(&identity).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCAuthResult{User: user, Identity: identity, Created: true}, nil
+}
+
+// exchangeCode menukar authorization code dengan token di token endpoint penyedia dan mengembalikan ID token
+func (p *OIDCProvider) exchangeCode(code, verifier string) (string, error) {
+	discovery, err := p.discovery()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	// Klien publik (tanpa secret) mengirim client_id di body; klien confidential memakai client_secret_basic
+	if p.ClientSecret == "" {
+		form.Set("client_id", p.ClientID)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+
+	resp, err := oidcHTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrOIDCExchangeFailed, err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("%w: invalid token response (status %d)", ErrOIDCExchangeFailed, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return "", fmt.Errorf("%w: %s %s", ErrOIDCExchangeFailed, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", fmt.Errorf("%w: token response has no id_token", ErrOIDCExchangeFailed)
+	}
+	return body.IDToken, nil
+}
+
+// validateIDToken memverifikasi tanda tangan ID token dengan JWKS penyedia dan memeriksa
+// iss, aud, azp, exp, iat, sub dan nonce
+func (p *OIDCProvider) validateIDToken(idToken, nonce string) (*oidcIDTokenClaims, error) {
+	claims := &oidcIDTokenClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods(oidcSigningMethods))
+	_, err := parser.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := p.verificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		if key.Alg != "" && key.Alg != token.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+		}
+		return key.PublicKey()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	now := time.Now()
+	switch {
+	case claims.Issuer != p.Issuer:
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, claims.Issuer)
+	case !claims.VerifyAudience(p.ClientID, true):
+		return nil, fmt.Errorf("%w: token was not issued for this client", ErrInvalidIDToken)
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != p.ClientID:
+		return nil, fmt.Errorf("%w: unexpected authorized party", ErrInvalidIDToken)
+	case claims.ExpiresAt == nil || !claims.VerifyExpiresAt(now, true):
+		return nil, fmt.Errorf("%w: token is expired", ErrInvalidIDToken)
+	case claims.IssuedAt == nil:
+		return nil, fmt.Errorf("%w: token has no iat", ErrInvalidIDToken)
+	case claims.Subject == "":
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidIDToken)
+	case subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1:
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	return claims, nil
+}
+
+// oidcMetadata menyimpan dokumen discovery dan JWKS penyedia di memori
+type oidcMetadata struct {
+	discovery     *oidcDiscovery
+	discoveredAt  time.Time
+	jwks          *utils.JWKS
+	jwksFetchedAt time.Time
+}
+
+var (
+	oidcCacheMu sync.Mutex
+	oidcCache   = map[string]*oidcMetadata{}
+)
+
+// oidcMetadataTTL mengembalikan lama cache discovery dan JWKS (default 1 jam)
+func oidcMetadataTTL() time.Duration {
+	return config.GetEnvDuration("OIDC_METADATA_CACHE_TTL", time.Hour)
+}
+
+// jwksRefreshInterval membatasi pengambilan ulang JWKS saat kid tidak dikenal (rotasi kunci di penyedia)
+const jwksRefreshInterval = time.Minute
+
+// cachedDiscovery mengembalikan dokumen discovery dari cache jika masih berlaku. Pemanggil harus memegang oidcCacheMu.
+func cachedDiscovery(issuer string) *oidcDiscovery {
+	meta := oidcCache[issuer]
+	if meta != nil && meta.discovery != nil && time.Since(meta.discoveredAt) < oidcMetadataTTL() {
+		return meta.discovery
+	}
+	return nil
+}
+
+// discovery mengambil dokumen /.well-known/openid-configuration penyedia (dengan cache).
+// Dokumen diambil tanpa memegang oidcCacheMu agar penyedia yang lambat tidak menahan penyedia lain.
+func (p *OIDCProvider) discovery() (*oidcDiscovery, error) {
+	oidcCacheMu.Lock()
+	cached := cachedDiscovery(p.Issuer)
+	oidcCacheMu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	if err := checkIssuerURL(p.Issuer); err != nil {
+		return nil, err
+	}
+
+	var discovery oidcDiscovery
+	if err := fetchJSON(p.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	// Issuer di dokumen harus sama persis dengan yang dikonfigurasi (OIDC Discovery 1.0 bagian 4.3)
+	if discovery.Issuer != p.Issuer {
+		return nil, fmt.Errorf("%w: discovery issuer %q does not match %q", ErrOIDCExchangeFailed, discovery.Issuer, p.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: discovery document is incomplete", ErrOIDCExchangeFailed)
+	}
+
+	oidcCacheMu.Lock()
+	defer oidcCacheMu.Unlock()
+
+	// Request lain mungkin sudah mengisi cache selama dokumen diambil
+	if cached := cachedDiscovery(p.Issuer); cached != nil {
+		return cached, nil
+	}
+	meta := oidcCache[p.Issuer]
+	if meta == nil {
+		meta = &oidcMetadata{}
+		oidcCache[p.Issuer] = meta
+	}
+	meta.discovery = &discovery
+	meta.discoveredAt = time.Now()
+	return &discovery, nil
+}
+
+// verificationKey mencari JWK penyedia berdasarkan kid; JWKS diambil ulang jika kid belum dikenal
+func (p *OIDCProvider) verificationKey(kid string) (*utils.JWK, error) {
+	discovery, err := p.discovery()
+	if err != nil {
+		return nil, err
+	}
+
+	oidcCacheMu.Lock()
+	meta := oidcCache[p.Issuer]
+	cached, fetchedAt := meta.jwks, meta.jwksFetchedAt
+	oidcCacheMu.Unlock()
+
+	if cached != nil && time.Since(fetchedAt) < oidcMetadataTTL() {
+		if key := findJWK(cached, kid); key != nil {
+			return key, nil
+		}
+		if time.Since(fetchedAt) < jwksRefreshInterval {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+	}
+
+	// JWKS diambil tanpa memegang oidcCacheMu, sama seperti dokumen discovery
+	var jwks utils.JWKS
+	if err := fetchJSON(discovery.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+
+	oidcCacheMu.Lock()
+	meta.jwks = &jwks
+	meta.jwksFetchedAt = time.Now()
+	oidcCacheMu.Unlock()
+
+	if key := findJWK(&jwks, kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// findJWK mencari kunci tanda tangan dengan kid tertentu; tanpa kid, hanya diterima jika JWKS berisi satu kunci
+func findJWK(jwks *utils.JWKS, kid string) *utils.JWK {
+	var candidates []utils.JWK
+	for _, key := range jwks.Keys {
+		if key.Use == "" || key.Use == "sig" {
+			candidates = append(candidates, key)
+		}
+	}
+	if kid == "" {
+		if len(candidates) == 1 {
+			return &candidates[0]
+		}
+		return nil
+	}
+	for i := range candidates {
+		if candidates[i].Kid == kid {
+			return &candidates[i]
+		}
+	}
+	return nil
+}
+
+// checkIssuerURL mewajibkan https, kecuali issuer berada di localhost (misalnya penyedia tiruan untuk pengujian)
+func checkIssuerURL(issuer string) error {
+	parsed, err := url.Parse(issuer)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("%w: invalid issuer URL %q", ErrOIDCExchangeFailed, issuer)
+	}
+	if parsed.Scheme == "https" {
+		return nil
+	}
+	host := parsed.Hostname()
+	if parsed.Scheme == "http" && (host == "localhost" || net.ParseIP(host).IsLoopback()) {
+		return nil
+	}
+	return fmt.Errorf("%w: issuer %q must use https", ErrOIDCExchangeFailed, issuer)
+}
+
+// fetchJSON mengambil dokumen JSON dari penyedia
+func fetchJSON(endpoint string, target interface{}) error {
+	resp, err := oidcHTTPClient.Get(endpoint)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOIDCExchangeFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: GET %s returned status %d", ErrOIDCExchangeFailed, endpoint, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(target); err != nil {
+		return fmt.Errorf("%w: invalid JSON from %s", ErrOIDCExchangeFailed, endpoint)
+	}
+	return nil
+}
@@ -0,0 +1,605 @@
+// services/passkeys.go
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"main/config"
+	"main/models"
+	"main/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidPasskeyChallenge dikembalikan jika challenge tidak dikenal, sudah dipakai, atau kedaluwarsa
+	ErrInvalidPasskeyChallenge = errors.New("invalid or expired passkey challenge")
+	// ErrInvalidPasskey dikembalikan jika respons authenticator tidak lolos verifikasi
+	ErrInvalidPasskey = errors.New("passkey verification failed")
+	// ErrPasskeyExists dikembalikan jika kredensial yang sama sudah terdaftar
+	ErrPasskeyExists = errors.New("this passkey is already registered")
+	// ErrPasskeyNotFound dikembalikan jika passkey tidak ada atau bukan milik pengguna
+	ErrPasskeyNotFound = errors.New("passkey not found")
+	// ErrPasskeyCloneDetected dikembalikan jika sign count tidak naik, tanda authenticator mungkin digandakan
+	ErrPasskeyCloneDetected = errors.New("passkey sign count did not increase; the authenticator may have been cloned")
+)
+
+// PasskeyCredentialDescriptor mengidentifikasi kredensial di opsi ceremony (PublicKeyCredentialDescriptor)
+type PasskeyCredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// PasskeyCreationOptions adalah opsi untuk navigator.credentials.create() (nilai biner dalam base64url)
+type PasskeyCreationOptions struct {
+	Challenge string `json:"challenge"`
+	RP        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	PubKeyCredParams []struct {
+		Type string `json:"type"`
+		Alg  int    `json:"alg"`
+	} `json:"pubKeyCredParams"`
+	Timeout                int64                         `json:"timeout"`
+	ExcludeCredentials     []PasskeyCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection struct {
+		ResidentKey      string `json:"residentKey"`
+		UserVerification string `json:"userVerification"`
+	} `json:"authenticatorSelection"`
+	Attestation string `json:"attestation"`
+}
+
+// PasskeyRequestOptions adalah opsi untuk navigator.credentials.get() (nilai biner dalam base64url)
+type PasskeyRequestOptions struct {
+	Challenge        string                        `json:"challenge"`
+	RPID             string                        `json:"rpId"`
+	Timeout          int64                         `json:"timeout"`
+	AllowCredentials []PasskeyCredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                        `json:"userVerification"`
+}
+
+// PasskeyRegistrationCredential adalah hasil navigator.credentials.create() dari browser (base64url)
+type PasskeyRegistrationCredential struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// PasskeyAssertionCredential adalah hasil navigator.credentials.get() dari browser (base64url)
+type PasskeyAssertionCredential struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// PasskeyLoginResult adalah hasil login dengan passkey
+type PasskeyLoginResult struct {
+	User         models.User
+	UserVerified bool // authenticator memverifikasi pengguna (PIN/biometrik), sehingga sudah multi-faktor
+}
+
+// clientData adalah isi clientDataJSON yang dibuat browser
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// WebAuthnRPID mengembalikan relying party ID (domain), default host dari APP_BASE_URL
+func WebAuthnRPID() string {
+	if rpID := config.GetEnv("WEBAUTHN_RP_ID", ""); rpID != "" {
+		return rpID
+	}
+	parsed, err := url.Parse(AppURL(""))
+	if err != nil {
+		return "localhost"
+	}
+	return parsed.Hostname()
+}
+
+// webAuthnOrigins mengembalikan origin yang boleh menjalankan ceremony, default origin dari APP_BASE_URL
+func webAuthnOrigins() []string {
+	defaultOrigin := AppURL("")
+	if parsed, err := url.Parse(defaultOrigin); err == nil {
+		defaultOrigin = parsed.Scheme + "://" + parsed.Host
+	}
+	return config.GetEnvList("WEBAUTHN_ORIGINS", []string{defaultOrigin})
+}
+
+// webAuthnUserVerification mengembalikan kebijakan verifikasi pengguna: "preferred" (default) atau "required"
+func webAuthnUserVerification() string {
+	if config.GetEnv("WEBAUTHN_USER_VERIFICATION", "preferred") == "required" {
+		return "required"
+	}
+	return "preferred"
+}
+
+// webAuthnTimeout mengembalikan masa berlaku challenge (default 5 menit)
+func webAuthnTimeout() time.Duration {
+	return config.GetEnvDuration("WEBAUTHN_CHALLENGE_TTL", 5*time.Minute)
+}
+
+// BeginPasskeyRegistration membuat challenge dan opsi registrasi passkey untuk pengguna yang sedang login
+func BeginPasskeyRegistration(userID uint) (*PasskeyCreationOptions, error) {
+	var user models.User
+	if err := config.DB.Preload("Passkeys").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	challenge, err := createWebAuthnChallenge(models.WebAuthnCeremonyRegistration, &userID)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &PasskeyCreationOptions{
+		Challenge:          challenge,
+		Timeout:            webAuthnTimeout().Milliseconds(),
+		ExcludeCredentials: credentialDescriptors(user.Passkeys),
+		Attestation:        "none",
+	}
+	options.RP.ID = WebAuthnRPID()
+	options.RP.Name = config.GetEnv("WEBAUTHN_RP_NAME", "User Preferences")
+	options.User.ID = base64.RawURLEncoding.EncodeToString(userHandle(user.ID))
+	options.User.Name = user.Username
+	options.User.DisplayName = user.Username
+	for _, alg := range []int{utils.COSEAlgES256, utils.COSEAlgEdDSA, utils.COSEAlgRS256} {
+		options.PubKeyCredParams = append(options.PubKeyCredParams, struct {
+			Type string `json:"type"`
+			Alg  int    `json:"alg"`
+		}{Type: "public-key", Alg: alg})
+	}
+	options.AuthenticatorSelection.ResidentKey = "preferred"
+	options.AuthenticatorSelection.UserVerification = webAuthnUserVerification()
+	return options, nil
+}
+
+// FinishPasskeyRegistration memverifikasi respons navigator.credentials.create() dan menyimpan passkey baru.
+// Attestation tidak diverifikasi (opsi attestation "none"): yang dibuktikan adalah kepemilikan kunci, bukan model authenticator.
+func FinishPasskeyRegistration(userID uint, name string, credential PasskeyRegistrationCredential) (*models.WebAuthnCredential, error) {
+	if credential.Type != "public-key" {
+		return nil, fmt.Errorf("%w: unexpected credential type", ErrInvalidPasskey)
+	}
+
+	clientDataJSON, err := decodeBase64URL(credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid clientDataJSON encoding", ErrInvalidPasskey)
+	}
+	challenge, err := verifyClientData(clientDataJSON, "webauthn.create", models.WebAuthnCeremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.UserID == nil || *challenge.UserID != userID {
+		return nil, ErrInvalidPasskeyChallenge
+	}
+
+	attestationObject, err := decodeBase64URL(credential.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid attestationObject encoding", ErrInvalidPasskey)
+	}
+	decoded, _, err := utils.DecodeCBOR(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: attestation object is not a map", ErrInvalidPasskey)
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%w: attestation object has no authData", ErrInvalidPasskey)
+	}
+
+	authData, err := verifyAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if len(authData.CredentialID) == 0 {
+		return nil, fmt.Errorf("%w: no attested credential data", ErrInvalidPasskey)
+	}
+
+	rawID, err := decodeBase64URL(credential.RawID)
+	if err != nil || !bytes.Equal(rawID, authData.CredentialID) {
+		return nil, fmt.Errorf("%w: credential id mismatch", ErrInvalidPasskey)
+	}
+
+	_, algorithm, err := utils.ParseCOSEKey(authData.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(authData.CredentialID)
+	var count int64
+	if err := config.DB.Model(&models.WebAuthnCredential{}).Where("credential_id = ?", credentialID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrPasskeyExists
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Passkey"
+	}
+
+	passkey := models.WebAuthnCredential{
+		UserID:         userID,
+		Name:           name,
+		CredentialID:   credentialID,
+		PublicKey:      authData.PublicKey,
+		Algorithm:      algorithm,
+		SignCount:      authData.SignCount,
+		AAGUID:         formatAAGUID(authData.AAGUID),
+		Transports:     credential.Response.Transports,
+		BackupEligible: authData.Flags&utils.AuthDataFlagBackupEligible != 0,
+		BackupState:    authData.Flags&utils.AuthDataFlagBackupState != 0,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	if err := config.DB.Create(&passkey).Error; err != nil {
+		return nil, err
+	}
+	return &passkey, nil
+}
+
+// BeginPasskeyLogin membuat challenge login. Jika username (atau email) diisi dan dikenal, passkey pengguna itu
+// dicantumkan di allowCredentials; jika tidak, browser memakai passkey discoverable.
+// Username yang tidak dikenal menghasilkan respons yang sama dengan tanpa username (tidak membocorkan akun).
+func BeginPasskeyLogin(username string) (*PasskeyRequestOptions, error) {
+	var userID *uint
+	var passkeys []models.WebAuthnCredential
+
+	if username != "" {
+		user, err := FindUserByIdentifier(config.DB.Preload("Passkeys"), username)
+		if err != nil && !errors.Is(err, ErrUserNotFound) {
+			return nil, err
+		}
+		if err == nil && len(user.Passkeys) > 0 {
+			userID = &user.ID
+			passkeys = user.Passkeys
+		}
+	}
+
+	challenge, err := createWebAuthnChallenge(models.WebAuthnCeremonyLogin, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PasskeyRequestOptions{
+		Challenge:        challenge,
+		RPID:             WebAuthnRPID(),
+		Timeout:          webAuthnTimeout().Milliseconds(),
+		AllowCredentials: credentialDescriptors(passkeys),
+		UserVerification: webAuthnUserVerification(),
+	}, nil
+}
+
+// FinishPasskeyLogin memverifikasi respons navigator.credentials.get(): challenge, origin, rpIdHash,
+// tanda tangan, dan sign count (deteksi authenticator yang digandakan)
+func FinishPasskeyLogin(credential PasskeyAssertionCredential) (*PasskeyLoginResult, error) {
+	if credential.Type != "public-key" {
+		return nil, fmt.Errorf("%w: unexpected credential type", ErrInvalidPasskey)
+	}
+
+	clientDataJSON, err := decodeBase64URL(credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid clientDataJSON encoding", ErrInvalidPasskey)
+	}
+	challenge, err := verifyClientData(clientDataJSON, "webauthn.get", models.WebAuthnCeremonyLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	rawID, err := decodeBase64URL(credential.RawID)
+	if err != nil || len(rawID) == 0 {
+		return nil, fmt.Errorf("%w: invalid credential id", ErrInvalidPasskey)
+	}
+
+	var passkey models.WebAuthnCredential
+	result := config.DB.Where("credential_id = ?", base64.RawURLEncoding.EncodeToString(rawID)).First(&passkey)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidPasskey
+		}
+		return nil, result.Error
+	}
+
+	// Challenge untuk username tertentu hanya bisa diselesaikan dengan passkey milik pengguna itu
+	if challenge.UserID != nil && *challenge.UserID != passkey.UserID {
+		return nil, ErrInvalidPasskey
+	}
+
+	// userHandle wajib untuk login tanpa username, dan jika ada harus cocok dengan pemilik kredensial
+	if credential.Response.UserHandle != "" {
+		handle, err := decodeBase64URL(credential.Response.UserHandle)
+		if err != nil || !bytes.Equal(handle, userHandle(passkey.UserID)) {
+			return nil, fmt.Errorf("%w: user handle mismatch", ErrInvalidPasskey)
+		}
+	} else if challenge.UserID == nil {
+		return nil, fmt.Errorf("%w: user handle is required", ErrInvalidPasskey)
+	}
+
+	rawAuthData, err := decodeBase64URL(credential.Response.AuthenticatorData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid authenticatorData encoding", ErrInvalidPasskey)
+	}
+	authData, err := verifyAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := decodeBase64URL(credential.Response.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid signature encoding", ErrInvalidPasskey)
+	}
+	if err := utils.VerifyCOSESignature(passkey.PublicKey, rawAuthData, clientDataJSON, signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+
+	if err := updateSignCount(&passkey, authData); err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, passkey.UserID).Error; err != nil {
+		// Passkey akun yang menunggu penghapusan permanen tidak bisa dipakai login
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidPasskey
+		}
+		return nil, err
+	}
+	return &PasskeyLoginResult{User: user, UserVerified: authData.UserVerified()}, nil
+}
+
+// ListPasskeys menampilkan semua passkey milik pengguna
+func ListPasskeys(userID uint) ([]models.WebAuthnCredential, error) {
+	var passkeys []models.WebAuthnCredential
+	err := config.DB.Where("user_id = ?", userID).Order("created_at").Find(&passkeys).Error
+	return passkeys, err
+}
+
+// RenamePasskey mengganti nama passkey milik pengguna
+func RenamePasskey(userID, passkeyID uint, name string) (*models.WebAuthnCredential, error) {
+	var passkey models.WebAuthnCredential
+	result := config.DB.Where("id = ? AND user_id = ?", passkeyID, userID).First(&passkey)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrPasskeyNotFound
+		}
+		return nil, result.Error
+	}
+
+	passkey.Name = name
+	passkey.UpdatedAt = time.Now()
+	if err := config.DB.Save(&passkey).Error; err != nil {
+		return nil, err
+	}
+	return &passkey, nil
+}
+
+// DeletePasskey menghapus passkey milik pengguna; passkey terakhir tidak bisa dihapus jika
+// tidak ada cara login lain (password atau identitas eksternal)
+func DeletePasskey(userID, passkeyID uint) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var passkey models.WebAuthnCredential
+		result := tx.Where("id = ? AND user_id = ?", passkeyID, userID).First(&passkey)
+		if result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return ErrPasskeyNotFound
+			}
+			return result.Error
+		}
+
+		methods, err := countLoginMethods(tx, userID)
+		if err != nil {
+			return err
+		}
+		if methods <= 1 {
+			return ErrLastLoginMethod
+		}
+
+		return tx.Delete(&passkey).Error
+	})
+}
+
+// createWebAuthnChallenge membuat challenge acak dan menyimpan hash-nya
+func createWebAuthnChallenge(ceremony string, userID *uint) (string, error) {
+	challenge, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	record := models.WebAuthnChallenge{
+		ChallengeHash: utils.HashToken(challenge),
+		Ceremony:      ceremony,
+		UserID:        userID,
+		ExpiresAt:     time.Now().Add(webAuthnTimeout()),
+		CreatedAt:     time.Now(),
+	}
+	if err := config.DB.Create(&record).Error; err != nil {
+		return "", err
+	}
+
+	// Bersihkan challenge yang kedaluwarsa tanpa menghambat request
+	go config.DB.Where("expires_at < ?", time.Now()).Delete(&models.WebAuthnChallenge{})
+
+	return challenge, nil
+}
+
+// verifyClientData memeriksa type dan origin di clientDataJSON, lalu mengambil dan menghapus challenge-nya
+func verifyClientData(raw []byte, expectedType, ceremony string) (*models.WebAuthnChallenge, error) {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("%w: invalid clientDataJSON", ErrInvalidPasskey)
+	}
+	if data.Type != expectedType {
+		return nil, fmt.Errorf("%w: unexpected client data type %q", ErrInvalidPasskey, data.Type)
+	}
+	if data.CrossOrigin {
+		return nil, fmt.Errorf("%w: cross-origin ceremonies are not allowed", ErrInvalidPasskey)
+	}
+
+	originAllowed := false
+	for _, origin := range webAuthnOrigins() {
+		if data.Origin == origin {
+			originAllowed = true
+			break
+		}
+	}
+	if !originAllowed {
+		return nil, fmt.Errorf("%w: origin %q is not allowed", ErrInvalidPasskey, data.Origin)
+	}
+
+	// Ambil dan hapus challenge secara atomik agar tidak bisa dipakai ulang
+	var challenge models.WebAuthnChallenge
+	result := config.DB.Clauses(clause.Returning{}).
+		Where("challenge_hash = ? AND ceremony = ?", utils.HashToken(data.Challenge), ceremony).
+		Delete(&challenge)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || time.Now().After(challenge.ExpiresAt) {
+		return nil, ErrInvalidPasskeyChallenge
+	}
+	return &challenge, nil
+}
+
+// verifyAuthenticatorData memeriksa rpIdHash serta flag user present/verified
+func verifyAuthenticatorData(raw []byte) (*utils.AuthenticatorData, error) {
+	authData, err := utils.ParseAuthenticatorData(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+
+	rpIDHash := sha256.Sum256([]byte(WebAuthnRPID()))
+	if !bytes.Equal(authData.RPIDHash, rpIDHash[:]) {
+		return nil, fmt.Errorf("%w: relying party id mismatch", ErrInvalidPasskey)
+	}
+	if !authData.UserPresent() {
+		return nil, fmt.Errorf("%w: user presence is required", ErrInvalidPasskey)
+	}
+	if webAuthnUserVerification() == "required" && !authData.UserVerified() {
+		return nil, fmt.Errorf("%w: user verification is required", ErrInvalidPasskey)
+	}
+	return authData, nil
+}
+
+// updateSignCount menyimpan sign count baru. Authenticator yang tidak memakai counter selalu mengirim 0;
+// selain itu counter harus selalu naik, jika tidak kredensial ditandai dan login ditolak.
+func updateSignCount(passkey *models.WebAuthnCredential, authData *utils.AuthenticatorData) error {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"sign_count":   authData.SignCount,
+		"backup_state": authData.Flags&utils.AuthDataFlagBackupState != 0,
+		"last_used_at": now,
+		"updated_at":   now,
+	}
+
+	query := config.DB.Model(&models.WebAuthnCredential{}).Where("id = ?", passkey.ID)
+	if authData.SignCount > 0 {
+		// Update bersyarat agar dua login paralel dengan counter yang sama tidak sama-sama lolos
+		query = query.Where("sign_count < ?", authData.SignCount)
+	} else {
+		query = query.Where("sign_count = 0")
+	}
+
+	result := query.Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if err := config.DB.Model(&models.WebAuthnCredential{}).Where("id = ?", passkey.ID).Update("clone_warning", true).Error; err != nil {
+			return err
+		}
+		return ErrPasskeyCloneDetected
+	}
+
+	passkey.SignCount = authData.SignCount
+	passkey.LastUsedAt = &now
+	return nil
+}
+
+// countLoginMethods menghitung cara login yang dimiliki pengguna: password, identitas eksternal dan passkey.
+// Tautan login email tidak dihitung karena hanya cadangan yang bergantung pada akses ke kotak surat.
+func countLoginMethods(tx *gorm.DB, userID uint) (int64, error) {
+	var user models.User
+	if err := tx.First(&user, userID).Error; err != nil {
+		return 0, err
+	}
+
+	var methods int64
+	if user.Password != "" {
+		methods++
+	}
+	for _, model := range []interface{}{&models.ExternalIdentity{}, &models.WebAuthnCredential{}} {
+		var count int64
+		if err := tx.Model(model).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return 0, err
+		}
+		methods += count
+	}
+	return methods, nil
+}
+
+// credentialDescriptors mengubah passkey tersimpan menjadi descriptor untuk opsi ceremony
+func credentialDescriptors(passkeys []models.WebAuthnCredential) []PasskeyCredentialDescriptor {
+	descriptors := []PasskeyCredentialDescriptor{}
+	for _, passkey := range passkeys {
+		descriptors = append(descriptors, PasskeyCredentialDescriptor{
+			Type:       "public-key",
+			ID:         passkey.CredentialID,
+			Transports: passkey.Transports,
+		})
+	}
+	return descriptors
+}
+
+// userHandle adalah ID pengguna WebAuthn: ID numerik 8 byte big-endian (tidak memuat data pribadi)
+func userHandle(userID uint) []byte {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(userID))
+	return handle
+}
+
+// formatAAGUID memformat AAGUID authenticator sebagai UUID
+func formatAAGUID(aaguid []byte) string {
+	if len(aaguid) != 16 {
+		return ""
+	}
+	h := hex.EncodeToString(aaguid)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// decodeBase64URL mendekode base64url dengan atau tanpa padding
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
@@ -0,0 +1,229 @@
+// services/password_policy.go
+package services
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"main/config"
+)
+
+// PasswordContext berisi data akun yang dipakai aturan password (misalnya untuk menolak password yang mirip username)
+type PasswordContext struct {
+	Username string
+	Email    string
+}
+
+// PasswordRule adalah satu aturan kebijakan password. Check mengembalikan nil jika password lolos,
+// atau FieldError (tanpa Field; diisi oleh PasswordPolicy) yang menjelaskan aturan yang gagal.
+type PasswordRule interface {
+	Check(password string, pctx PasswordContext) (*FieldError, error)
+}
+
+// PasswordPolicy menjalankan semua aturan dan mengumpulkan aturan yang gagal
+type PasswordPolicy struct {
+	Rules []PasswordRule
+}
+
+// Validate memeriksa password terhadap semua aturan. Jika ada yang gagal, hasilnya *ValidationError
+// dengan satu FieldError per aturan untuk field yang diberikan.
+func (p *PasswordPolicy) Validate(field, password string, pctx PasswordContext) error {
+	var failures []FieldError
+	for _, rule := range p.Rules {
+		failure, err := rule.Check(password, pctx)
+		if err != nil {
+			return err
+		}
+		if failure != nil {
+			failure.Field = field
+			failures = append(failures, *failure)
+		}
+	}
+	if len(failures) > 0 {
+		return &ValidationError{Fields: failures}
+	}
+	return nil
+}
+
+// LengthRule mewajibkan panjang password (dalam karakter) di antara Min dan Max
+type LengthRule struct {
+	Min int
+	Max int
+}
+
+// Check memeriksa panjang password
+func (r LengthRule) Check(password string, pctx PasswordContext) (*FieldError, error) {
+	length := utf8.RuneCountInString(password)
+	if length < r.Min {
+		return &FieldError{Code: "password_too_short", Message: fmt.Sprintf("must be at least %d characters long", r.Min)}, nil
+	}
+	if r.Max > 0 && length > r.Max {
+		return &FieldError{Code: "password_too_long", Message: fmt.Sprintf("must be at most %d characters long", r.Max)}, nil
+	}
+	return nil, nil
+}
+
+// CharacterClassRule mewajibkan password memakai minimal Min dari empat jenis karakter:
+// huruf kecil, huruf besar, angka dan simbol
+type CharacterClassRule struct {
+	Min int
+}
+
+// Check menghitung jenis karakter yang dipakai password
+func (r CharacterClassRule) Check(password string, pctx PasswordContext) (*FieldError, error) {
+	var lower, upper, digit, symbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsDigit(c):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, used := range []bool{lower, upper, digit, symbol} {
+		if used {
+			classes++
+		}
+	}
+	if classes < r.Min {
+		return &FieldError{
+			Code:    "password_too_simple",
+			Message: fmt.Sprintf("must contain at least %d of: lowercase letters, uppercase letters, digits, symbols", r.Min),
+		}, nil
+	}
+	return nil, nil
+}
+
+// SimilarityRule menolak password yang memuat username atau bagian lokal email (atau sebaliknya)
+type SimilarityRule struct{}
+
+// Check membandingkan password dengan username dan email tanpa membedakan huruf besar/kecil
+func (SimilarityRule) Check(password string, pctx PasswordContext) (*FieldError, error) {
+	normalized := strings.ToLower(password)
+	localPart := strings.SplitN(pctx.Email, "@", 2)[0]
+
+	for _, identifier := range []string{pctx.Username, localPart, pctx.Email} {
+		identifier = strings.ToLower(strings.TrimSpace(identifier))
+		if utf8.RuneCountInString(identifier) < 3 {
+			continue
+		}
+		if strings.Contains(normalized, identifier) ||
+			(utf8.RuneCountInString(normalized) >= 3 && strings.Contains(identifier, normalized)) {
+			return &FieldError{Code: "password_too_similar", Message: "must not contain your username or email address"}, nil
+		}
+	}
+	return nil, nil
+}
+
+// BreachedPasswordRule menolak password yang ada di daftar password bocor. Daftar disimpan di Dir dalam
+// format k-anonymity (seperti range API Have I Been Pwned): satu file per 5 karakter awal hash SHA-1
+// (mis. "21BD1" atau "21BD1.txt"), berisi baris "SISA_HASH:JUMLAH". Hanya file untuk prefix password
+// yang diperiksa yang dibaca.
+type BreachedPasswordRule struct {
+	Dir      string
+	MinCount int // jumlah kemunculan minimum agar password dianggap bocor
+}
+
+// Check mencari hash password di file prefix-nya
+func (r BreachedPasswordRule) Check(password string, pctx PasswordContext) (*FieldError, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := r.openPrefix(prefix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		candidate, count, _ := strings.Cut(line, ":")
+		if !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+
+		occurrences, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil {
+			occurrences = 1
+		}
+		if occurrences >= r.MinCount {
+			return &FieldError{
+				Code:    "password_breached",
+				Message: "appears in a list of passwords exposed in data breaches; choose a different password",
+			}, nil
+		}
+		return nil, nil
+	}
+	return nil, scanner.Err()
+}
+
+// openPrefix membuka file untuk prefix hash, dengan atau tanpa ekstensi .txt
+func (r BreachedPasswordRule) openPrefix(prefix string) (*os.File, error) {
+	file, err := os.Open(filepath.Join(r.Dir, prefix+".txt"))
+	if os.IsNotExist(err) {
+		return os.Open(filepath.Join(r.Dir, prefix))
+	}
+	return file, err
+}
+
+var (
+	passwordPolicyOnce sync.Once
+	passwordPolicy     *PasswordPolicy
+)
+
+// DefaultPasswordPolicy mengembalikan kebijakan password sesuai konfigurasi PASSWORD_* di environment
+func DefaultPasswordPolicy() *PasswordPolicy {
+	passwordPolicyOnce.Do(func() {
+		rules := []PasswordRule{
+			LengthRule{
+				Min: config.GetEnvInt("PASSWORD_MIN_LENGTH", 8),
+				Max: config.GetEnvInt("PASSWORD_MAX_LENGTH", 128),
+			},
+		}
+		if classes := config.GetEnvInt("PASSWORD_MIN_CHARACTER_CLASSES", 0); classes > 0 {
+			rules = append(rules, CharacterClassRule{Min: classes})
+		}
+		if config.GetEnvBool("PASSWORD_REJECT_SIMILAR", true) {
+			rules = append(rules, SimilarityRule{})
+		}
+		if dir := config.GetEnv("PASSWORD_BREACH_DIR", ""); dir != "" {
+			rules = append(rules, BreachedPasswordRule{
+				Dir:      dir,
+				MinCount: config.GetEnvInt("PASSWORD_BREACH_MIN_COUNT", 1),
+			})
+		}
+		passwordPolicy = &PasswordPolicy{Rules: rules}
+	})
+	return passwordPolicy
+}
+
+// SetPasswordPolicy mengganti kebijakan password default (misalnya untuk menambah aturan khusus)
+func SetPasswordPolicy(p *PasswordPolicy) {
+	passwordPolicyOnce.Do(func() {})
+	passwordPolicy = p
+}
+
+// ValidatePassword memeriksa password baru terhadap kebijakan password default
+func ValidatePassword(field, password string, pctx PasswordContext) error {
+	return DefaultPasswordPolicy().Validate(field, password, pctx)
+}
@@ -0,0 +1,133 @@
+// services/password_reset.go
+package services
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"time"
+
+	"main/config"
+	"main/models"
+	"main/utils"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidResetToken dikembalikan jika token reset tidak dikenal, kedaluwarsa, atau sudah dipakai
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+// PasswordResetTTL mengembalikan masa berlaku token reset password (default 1 jam)
+func PasswordResetTTL() time.Duration {
+	return config.GetEnvDuration("PASSWORD_RESET_TTL", time.Hour)
+}
+
+// RequestPasswordReset membuat token reset dan mengirim tautan reset ke email pengguna di background.
+// Pencarian akun, penyimpanan token dan pengiriman email tidak ditunggu sehingga waktu respons sama
+// untuk email yang terdaftar maupun tidak, dan keberadaan akun tidak bocor.
+func RequestPasswordReset(email string) {
+	go func() {
+		if err := createPasswordReset(email); err != nil {
+			log.Printf("Failed to create password reset token: %v", err)
+		}
+	}()
+}
+
+// createPasswordReset menyimpan token reset baru dan mengirim tautannya; email yang tidak terdaftar diabaikan
+func createPasswordReset(email string) error {
+	var user models.User
+	result := config.DB.Where("email_canonical = ?", utils.CanonicalIdentifier(email)).First(&user)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return result.Error
+	}
+
+	rawToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Token lama yang belum dipakai dibatalkan sehingga hanya tautan terbaru yang berlaku
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: utils.HashToken(rawToken),
+			ExpiresAt: time.Now().Add(PasswordResetTTL()),
+			CreatedAt: time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return DefaultMailer().Send(MailMessage{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: "Hi " + user.Username + ",\n\n" +
+			"Use the link below to choose a new password. The link expires in " + PasswordResetTTL().String() + " and can only be used once.\n\n" +
+			AppURL("/reset-password?token="+url.QueryEscape(rawToken)) + "\n\n" +
+			"If you did not request a password reset, you can ignore this email.",
+	})
+}
+
+// ResetPassword mengganti password pengguna menggunakan token reset, lalu mencabut semua sesi pengguna
+func ResetPassword(rawToken, newPassword string) error {
+	var token models.PasswordResetToken
+	result := config.DB.Where("token_hash = ?", utils.HashToken(rawToken)).First(&token)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		return result.Error
+	}
+
+	if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Tandai token sebagai terpakai secara atomik agar tidak bisa dipakai dua kali
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidResetToken
+		}
+
+		var user models.User
+		if err := tx.First(&user, token.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidResetToken
+			}
+			return err
+		}
+
+		// Password yang ditolak kebijakan membatalkan transaksi sehingga token masih bisa dipakai lagi
+		pctx := PasswordContext{Username: user.Username, Email: user.Email}
+		if err := ValidatePassword("password", newPassword, pctx); err != nil {
+			return err
+		}
+
+		if err := user.SetPassword(newPassword); err != nil {
+			return err
+		}
+		return tx.Model(&user).Update("password", user.Password).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// Semua sesi lama dicabut karena password mungkin bocor
+	return RevokeAllUserTokens(token.UserID)
+}
@@ -0,0 +1,314 @@
+// services/preference_events.go
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"main/config"
+	"main/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PreferenceEvent memberi tahu bahwa preferensi pengguna berubah. Satu event mewakili satu versi
+// di riwayat preferensi, sehingga klien yang terputus bisa melanjutkan dari versi terakhir yang diterimanya.
+type PreferenceEvent struct {
+	UserID    uint                       `json:"-"`
+	Version   int64                      `json:"version"`
+	Source    string                     `json:"source"`
+	ActorID   *uint                      `json:"actor_id"`
+	Changes   []models.PreferenceHistory `json:"changes"`
+	ChangedAt time.Time                  `json:"changed_at"`
+}
+
+// PreferenceBroker adalah antarmuka pub/sub perubahan preferensi sehingga implementasinya bisa diganti
+// (in-process untuk satu instance, Postgres LISTEN/NOTIFY untuk beberapa instance)
+type PreferenceBroker interface {
+	Publish(event PreferenceEvent) error
+	// Subscribe mengembalikan channel event untuk satu pengguna dan fungsi untuk berhenti berlangganan.
+	// Channel ditutup jika pelanggan terlalu lambat membaca; klien harus tersambung ulang dan melanjutkan dari Last-Event-ID.
+	Subscribe(userID uint) (<-chan PreferenceEvent, func())
+}
+
+// preferenceSubscriberBuffer adalah jumlah event yang boleh menumpuk sebelum pelanggan dianggap terlalu lambat
+const preferenceSubscriberBuffer = 16
+
+// MemoryPreferenceBroker menyebarkan event ke pelanggan di proses yang sama
+type MemoryPreferenceBroker struct {
+	mu          sync.Mutex
+	subscribers map[uint]map[chan PreferenceEvent]struct{}
+}
+
+// NewMemoryPreferenceBroker membuat broker in-process yang kosong
+func NewMemoryPreferenceBroker() *MemoryPreferenceBroker {
+	return &MemoryPreferenceBroker{subscribers: map[uint]map[chan PreferenceEvent]struct{}{}}
+}
+
+// Publish mengirim event ke semua pelanggan milik pengguna tanpa menunggu pelanggan yang lambat
+func (b *MemoryPreferenceBroker) Publish(event PreferenceEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[event.UserID] {
+		select {
+		case ch <- event:
+		default:
+			// Buffer penuh: putuskan pelanggan agar penerbit tidak tertahan
+			b.remove(event.UserID, ch)
+		}
+	}
+	return nil
+}
+
+// Subscribe mendaftarkan pelanggan baru untuk event milik pengguna
+func (b *MemoryPreferenceBroker) Subscribe(userID uint) (<-chan PreferenceEvent, func()) {
+	ch := make(chan PreferenceEvent, preferenceSubscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = map[chan PreferenceEvent]struct{}{}
+	}
+	b.subscribers[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(userID, ch)
+	}
+	return ch, cancel
+}
+
+// CloseAll memutus semua pelanggan; klien tersambung ulang dan menyusul dari Last-Event-ID
+func (b *MemoryPreferenceBroker) CloseAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for userID, subscribers := range b.subscribers {
+		for ch := range subscribers {
+			b.remove(userID, ch)
+		}
+	}
+}
+
+// remove menghapus dan menutup channel pelanggan (jika masih terdaftar). Pemanggil harus memegang b.mu.
+func (b *MemoryPreferenceBroker) remove(userID uint, ch chan PreferenceEvent) {
+	if _, ok := b.subscribers[userID][ch]; !ok {
+		return
+	}
+	delete(b.subscribers[userID], ch)
+	if len(b.subscribers[userID]) == 0 {
+		delete(b.subscribers, userID)
+	}
+	close(ch)
+}
+
+// PostgresPreferenceBroker menyebarkan event ke semua instance melalui Postgres LISTEN/NOTIFY.
+// Payload NOTIFY hanya berisi user ID dan versi; setiap instance memuat isi event dari riwayat preferensi
+// lalu meneruskannya ke pelanggan lokalnya.
+type PostgresPreferenceBroker struct {
+	DSN     string
+	Channel string
+
+	local     *MemoryPreferenceBroker
+	startOnce sync.Once
+
+	// listening ditutup selama koneksi LISTEN aktif dan diganti channel baru saat koneksi terputus
+	mu        sync.Mutex
+	listening chan struct{}
+}
+
+// preferenceListenTimeout adalah batas waktu Subscribe menunggu koneksi LISTEN siap
+const preferenceListenTimeout = 5 * time.Second
+
+// preferenceNotification adalah payload NOTIFY untuk satu perubahan preferensi
+type preferenceNotification struct {
+	UserID  uint  `json:"user_id"`
+	Version int64 `json:"version"`
+}
+
+// NewPostgresPreferenceBroker membuat broker LISTEN/NOTIFY pada channel tertentu
+func NewPostgresPreferenceBroker(dsn, channel string) *PostgresPreferenceBroker {
+	return &PostgresPreferenceBroker{
+		DSN:       dsn,
+		Channel:   channel,
+		local:     NewMemoryPreferenceBroker(),
+		listening: make(chan struct{}),
+	}
+}
+
+// Publish mengirim NOTIFY; event diteruskan ke pelanggan (termasuk di instance ini) oleh listener
+func (b *PostgresPreferenceBroker) Publish(event PreferenceEvent) error {
+	payload, err := json.Marshal(preferenceNotification{UserID: event.UserID, Version: event.Version})
+	if err != nil {
+		return err
+	}
+	return config.DB.Exec("SELECT pg_notify(?, ?)", b.Channel, string(payload)).Error
+}
+
+// Subscribe mendaftarkan pelanggan lokal setelah koneksi LISTEN siap, sehingga tidak ada notifikasi
+// yang terlewat setelah Subscribe kembali. Jika listener belum siap dalam preferenceListenTimeout,
+// channel yang dikembalikan sudah tertutup dan klien harus tersambung ulang.
+func (b *PostgresPreferenceBroker) Subscribe(userID uint) (<-chan PreferenceEvent, func()) {
+	b.startOnce.Do(func() {
+		go b.listen()
+	})
+
+	b.mu.Lock()
+	listening := b.listening
+	b.mu.Unlock()
+
+	select {
+	case <-listening:
+		return b.local.Subscribe(userID)
+	case <-time.After(preferenceListenTimeout):
+		ch := make(chan PreferenceEvent)
+		close(ch)
+		return ch, func() {}
+	}
+}
+
+// listen menjaga koneksi LISTEN tetap hidup dan tersambung ulang jika terputus. Notifikasi selama
+// koneksi terputus hilang, jadi semua pelanggan lokal diputus agar menyusul dari Last-Event-ID.
+func (b *PostgresPreferenceBroker) listen() {
+	for {
+		if err := b.listenOnce(context.Background()); err != nil {
+			log.Printf("Preference event listener stopped: %v; reconnecting", err)
+		}
+
+		b.mu.Lock()
+		select {
+		case <-b.listening:
+			b.listening = make(chan struct{})
+		default:
+		}
+		b.mu.Unlock()
+		b.local.CloseAll()
+
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// listenOnce membuka koneksi khusus (di luar pool GORM), menjalankan LISTEN, lalu meneruskan setiap notifikasi
+func (b *PostgresPreferenceBroker) listenOnce(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, b.DSN)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{b.Channel}.Sanitize()); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	close(b.listening)
+	b.mu.Unlock()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var payload preferenceNotification
+		if err := json.Unmarshal([]byte(notification.Payload), &payload); err != nil {
+			log.Printf("Ignoring malformed preference notification %q: %v", notification.Payload, err)
+			continue
+		}
+
+		events, err := preferenceEvents(payload.UserID, payload.Version, payload.Version)
+		if err != nil {
+			log.Printf("Failed to load preference event for user %d: %v", payload.UserID, err)
+			continue
+		}
+		for _, event := range events {
+			b.local.Publish(event)
+		}
+	}
+}
+
+var (
+	preferenceBrokerOnce sync.Once
+	preferenceBroker     PreferenceBroker
+)
+
+// DefaultPreferenceBroker mengembalikan broker sesuai konfigurasi PREFERENCE_EVENTS_BROKER (memory atau postgres)
+func DefaultPreferenceBroker() PreferenceBroker {
+	preferenceBrokerOnce.Do(func() {
+		switch config.GetEnv("PREFERENCE_EVENTS_BROKER", "memory") {
+		case "postgres":
+			preferenceBroker = NewPostgresPreferenceBroker(
+				config.DatabaseDSN(),
+				config.GetEnv("PREFERENCE_EVENTS_CHANNEL", "preference_changes"),
+			)
+		default:
+			preferenceBroker = NewMemoryPreferenceBroker()
+		}
+	})
+	return preferenceBroker
+}
+
+// SetPreferenceBroker mengganti broker default (misalnya dengan implementasi palsu saat pengujian)
+func SetPreferenceBroker(b PreferenceBroker) {
+	preferenceBrokerOnce.Do(func() {})
+	preferenceBroker = b
+}
+
+// PreferenceStreamHeartbeat mengembalikan interval komentar heartbeat di stream SSE (default 25 detik),
+// cukup pendek agar koneksi tidak diputus proxy karena idle
+func PreferenceStreamHeartbeat() time.Duration {
+	return config.GetEnvDuration("PREFERENCE_STREAM_HEARTBEAT", 25*time.Second)
+}
+
+// PreferenceEventsSince mengambil event untuk semua versi setelah version, untuk melanjutkan stream dari Last-Event-ID
+func PreferenceEventsSince(userID uint, version int64) ([]PreferenceEvent, error) {
+	return preferenceEvents(userID, version+1, 0)
+}
+
+// preferenceEvents menyusun event dari riwayat untuk versi from sampai to (0 berarti tanpa batas atas)
+func preferenceEvents(userID uint, from, to int64) ([]PreferenceEvent, error) {
+	db := config.DB.Where("user_id = ? AND version >= ?", userID, from)
+	if to > 0 {
+		db = db.Where("version <= ?", to)
+	}
+
+	var history []models.PreferenceHistory
+	if err := db.Order("version, id").Find(&history).Error; err != nil {
+		return nil, err
+	}
+	return groupPreferenceEvents(history), nil
+}
+
+// groupPreferenceEvents mengelompokkan entri riwayat (urut versi) menjadi satu event per versi
+func groupPreferenceEvents(history []models.PreferenceHistory) []PreferenceEvent {
+	var events []PreferenceEvent
+	for _, entry := range history {
+		if len(events) == 0 || events[len(events)-1].Version != entry.Version {
+			events = append(events, PreferenceEvent{
+				UserID:    entry.UserID,
+				Version:   entry.Version,
+				Source:    entry.Source,
+				ActorID:   entry.ActorID,
+				ChangedAt: entry.CreatedAt,
+			})
+		}
+		last := &events[len(events)-1]
+		last.Changes = append(last.Changes, entry)
+	}
+	return events
+}
+
+// publishPreferenceChange menyiarkan entri riwayat yang baru disimpan. Kegagalan hanya dicatat:
+// perubahan sudah tersimpan dan klien tetap bisa menyusul lewat Last-Event-ID.
+func publishPreferenceChange(entries []models.PreferenceHistory) {
+	for _, event := range groupPreferenceEvents(entries) {
+		if err := DefaultPreferenceBroker().Publish(event); err != nil {
+			log.Printf("Failed to publish preference change for user %d: %v", event.UserID, err)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// services/preference_patch.go
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"main/models"
+)
+
+// ErrPreferencePatchTestFailed dikembalikan jika operasi "test" JSON Patch tidak cocok dengan nilai saat ini
+var ErrPreferencePatchTestFailed = errors.New("patch test operation failed")
+
+// JSONPatchOperation adalah satu operasi JSON Patch (RFC 6902). Value bernilai nil jika field "value" tidak
+// dikirim, sehingga bisa dibedakan dari nilai null.
+type JSONPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// PreferencePatchError menandakan dokumen patch yang tidak sesuai format (operasi tidak dikenal,
+// field wajib tidak ada, JSON Pointer tidak valid)
+type PreferencePatchError struct {
+	Index   int
+	Message string
+}
+
+// Error mengembalikan pesan error
+func (e *PreferencePatchError) Error() string {
+	return fmt.Sprintf("operation %d: %s", e.Index, e.Message)
+}
+
+// MergePatchPreferences menerapkan JSON Merge Patch (RFC 7396): key bernilai null dikembalikan ke default,
+// key lain diubah, key yang tidak disebut tidak berubah. Field metadata (id, version, ...) diabaikan.
+func MergePatchPreferences(userID uint, patch map[string]interface{}, change PreferenceChangeContext) (*models.UserPreferences, error) {
+	return updatePreferences(userID, change, func(*models.UserPreferences) (map[string]interface{}, []string, error) {
+		set := map[string]interface{}{}
+		var unset []string
+		for key, value := range patch {
+			switch {
+			case models.IsPreferenceMetadataKey(key):
+				continue
+			case value == nil:
+				unset = append(unset, key)
+			default:
+				set[key] = value
+			}
+		}
+		return set, unset, nil
+	})
+}
+
+// ReplacePreferences mengganti seluruh preferensi (PUT): key yang tidak disebut dikembalikan ke default.
+// Field metadata (id, version, ...) diabaikan agar klien bisa mengirim balik representasi dari GET.
+func ReplacePreferences(userID uint, values map[string]interface{}, change PreferenceChangeContext) (*models.UserPreferences, error) {
+	return updatePreferences(userID, change, func(*models.UserPreferences) (map[string]interface{}, []string, error) {
+		set := map[string]interface{}{}
+		for key, value := range values {
+			if !models.IsPreferenceMetadataKey(key) {
+				set[key] = value
+			}
+		}
+		var unset []string
+		for _, def := range models.PreferenceDefinitions() {
+			if _, ok := set[def.Key]; !ok {
+				unset = append(unset, def.Key)
+			}
+		}
+		return set, unset, nil
+	})
+}
+
+// JSONPatchPreferences menerapkan JSON Patch (RFC 6902) secara atomik terhadap representasi datar preferensi.
+// "remove" mengembalikan preferensi ke default; "test" juga bisa memeriksa field metadata seperti /version.
+func JSONPatchPreferences(userID uint, operations []JSONPatchOperation, change PreferenceChangeContext) (*models.UserPreferences, error) {
+	return updatePreferences(userID, change, func(current *models.UserPreferences) (map[string]interface{}, []string, error) {
+		doc, err := preferenceDocument(current)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		touched := map[string]bool{}
+		removed := map[string]bool{}
+		for i, op := range operations {
+			if err := applyPatchOperation(doc, touched, removed, i, op); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		set := map[string]interface{}{}
+		var unset []string
+		for key := range touched {
+			if removed[key] {
+				unset = append(unset, key)
+			} else {
+				set[key] = doc[key]
+			}
+		}
+		return set, unset, nil
+	})
+}
+
+// applyPatchOperation menerapkan satu operasi ke dokumen kerja, mencatat key yang diubah (touched)
+// dan yang dikembalikan ke default (removed)
+func applyPatchOperation(doc map[string]interface{}, touched, removed map[string]bool, index int, op JSONPatchOperation) error {
+	malformed := func(message string) error {
+		return &PreferencePatchError{Index: index, Message: message}
+	}
+
+	key, err := patchPointerKey(op.Path)
+	if err != nil {
+		return malformed("path: " + err.Error())
+	}
+
+	var value interface{}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return malformed(`"value" is required for ` + op.Op)
+		}
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return malformed("invalid value: " + err.Error())
+		}
+	case "move", "copy":
+		from, err := patchPointerKey(op.From)
+		if err != nil {
+			return malformed("from: " + err.Error())
+		}
+		if err := checkPatchTarget(doc, from, op.Op == "move"); err != nil {
+			return err
+		}
+		value = doc[from]
+		if op.Op == "move" {
+			if from == key {
+				return nil
+			}
+			resetPatchKey(doc, touched, removed, from)
+		}
+	case "remove":
+	default:
+		return malformed(fmt.Sprintf("unsupported op %q", op.Op))
+	}
+
+	if op.Op == "test" {
+		if err := checkPatchTarget(doc, key, false); err != nil {
+			return err
+		}
+		if !jsonEqual(doc[key], value) {
+			return fmt.Errorf("operation %d (test %s): %w", index, op.Path, ErrPreferencePatchTestFailed)
+		}
+		return nil
+	}
+
+	if err := checkPatchTarget(doc, key, true); err != nil {
+		return err
+	}
+	if op.Op == "remove" {
+		resetPatchKey(doc, touched, removed, key)
+		return nil
+	}
+	doc[key] = value
+	touched[key] = true
+	removed[key] = false
+	return nil
+}
+
+// checkPatchTarget memastikan key adalah preferensi terdaftar (atau field metadata jika hanya dibaca)
+func checkPatchTarget(doc map[string]interface{}, key string, write bool) error {
+	if models.IsPreferenceMetadataKey(key) {
+		if write {
+			return &ValidationError{Fields: []FieldError{{Field: key, Code: "read_only", Message: "cannot be modified"}}}
+		}
+		return nil
+	}
+	if _, ok := doc[key]; !ok {
+		return &ValidationError{Fields: []FieldError{{Field: key, Code: "unknown_field", Message: "is not a known preference"}}}
+	}
+	return nil
+}
+
+// resetPatchKey mengembalikan key ke nilai default-nya di dokumen kerja
+func resetPatchKey(doc map[string]interface{}, touched, removed map[string]bool, key string) {
+	if def, ok := models.LookupPreference(key); ok {
+		doc[key] = jsonValue(def.Default)
+	}
+	touched[key] = true
+	removed[key] = true
+}
+
+// patchPointerKey mengubah JSON Pointer satu tingkat (mis. "/theme") menjadi key preferensi.
+// Representasi preferensi datar, sehingga path root atau bertingkat tidak didukung.
+func patchPointerKey(pointer string) (string, error) {
+	if !strings.HasPrefix(pointer, "/") {
+		return "", fmt.Errorf("%q is not a JSON pointer to a preference", pointer)
+	}
+	key := pointer[1:]
+	if key == "" || strings.Contains(key, "/") {
+		return "", fmt.Errorf("%q must point to a single preference such as /theme", pointer)
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~1", "/"), "~0", "~"), nil
+}
+
+// preferenceDocument mengembalikan representasi JSON datar preferensi sebagai map nilai JSON generik
+func preferenceDocument(preferences *models.UserPreferences) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(preferences)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(encoded, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// jsonValue mengubah nilai Go menjadi bentuk hasil decode JSON (mis. int64 menjadi float64)
+func jsonValue(value interface{}) interface{} {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return value
+	}
+	return decoded
+}
+
+// jsonEqual membandingkan dua nilai JSON sesuai aturan operasi "test"
+func jsonEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(jsonValue(a), jsonValue(b))
+}
@@ -0,0 +1,251 @@
+// services/preference_patch_test.go
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPatchPointerKey(t *testing.T) {
+	tests := []struct {
+		pointer string
+		want    string
+		wantErr bool
+	}{
+		{pointer: "/theme", want: "theme"},
+		{pointer: "/a~1b", want: "a/b"},
+		{pointer: "/a~0b", want: "a~b"},
+		{pointer: "/~01", want: "~1"},
+		{pointer: "/~10", want: "/0"},
+		{pointer: "theme", wantErr: true},
+		{pointer: "", wantErr: true},
+		{pointer: "/", wantErr: true},
+		{pointer: "/theme/0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pointer, func(t *testing.T) {
+			got, err := patchPointerKey(tt.pointer)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("patchPointerKey(%q) error = %v, wantErr %v", tt.pointer, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("patchPointerKey(%q) = %q, want %q", tt.pointer, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPatchOperation(t *testing.T) {
+	tests := []struct {
+		name        string
+		ops         string
+		wantDoc     map[string]interface{} // hanya key yang diperiksa
+		wantSet     []string
+		wantUnset   []string
+		wantErr     error
+		wantMessage bool   // PreferencePatchError
+		wantField   string // ValidationError
+	}{
+		{
+			name:    "replace",
+			ops:     `[{"op":"replace","path":"/theme","value":"dark"}]`,
+			wantDoc: map[string]interface{}{"theme": "dark"},
+			wantSet: []string{"theme"},
+		},
+		{
+			name:    "add behaves like replace",
+			ops:     `[{"op":"add","path":"/language","value":"french"}]`,
+			wantDoc: map[string]interface{}{"language": "french"},
+			wantSet: []string{"language"},
+		},
+		{
+			name:      "remove resets to default",
+			ops:       `[{"op":"remove","path":"/theme"}]`,
+			wantDoc:   map[string]interface{}{"theme": "light"},
+			wantUnset: []string{"theme"},
+		},
+		{
+			name:    "later operations see earlier ones",
+			ops:     `[{"op":"replace","path":"/theme","value":"dark"},{"op":"test","path":"/theme","value":"dark"}]`,
+			wantDoc: map[string]interface{}{"theme": "dark"},
+			wantSet: []string{"theme"},
+		},
+		{
+			name:    "test passes on metadata",
+			ops:     `[{"op":"test","path":"/version","value":3}]`,
+			wantDoc: map[string]interface{}{"version": float64(3)},
+		},
+		{
+			name:    "test compares JSON values",
+			ops:     `[{"op":"test","path":"/notifications","value":false}]`,
+			wantDoc: map[string]interface{}{"notifications": false},
+		},
+		{
+			name:    "test failure",
+			ops:     `[{"op":"replace","path":"/theme","value":"dark"},{"op":"test","path":"/language","value":"german"}]`,
+			wantErr: ErrPreferencePatchTestFailed,
+		},
+		{
+			name:    "test null against a set value",
+			ops:     `[{"op":"test","path":"/theme","value":null}]`,
+			wantErr: ErrPreferencePatchTestFailed,
+		},
+		{
+			name:      "move resets the source",
+			ops:       `[{"op":"move","from":"/language","path":"/theme"}]`,
+			wantDoc:   map[string]interface{}{"theme": "spanish", "language": "english"},
+			wantSet:   []string{"theme"},
+			wantUnset: []string{"language"},
+		},
+		{
+			name:    "move onto itself is a no-op",
+			ops:     `[{"op":"move","from":"/theme","path":"/theme"}]`,
+			wantDoc: map[string]interface{}{"theme": "dark"},
+		},
+		{
+			name:    "copy keeps the source",
+			ops:     `[{"op":"copy","from":"/language","path":"/theme"}]`,
+			wantDoc: map[string]interface{}{"theme": "spanish", "language": "spanish"},
+			wantSet: []string{"theme"},
+		},
+		{
+			name:    "copy from metadata",
+			ops:     `[{"op":"copy","from":"/version","path":"/theme"}]`,
+			wantDoc: map[string]interface{}{"theme": float64(3)},
+			wantSet: []string{"theme"},
+		},
+		{
+			name:      "move from metadata",
+			ops:       `[{"op":"move","from":"/version","path":"/theme"}]`,
+			wantField: "version",
+		},
+		{
+			name:      "write to metadata",
+			ops:       `[{"op":"replace","path":"/version","value":4}]`,
+			wantField: "version",
+		},
+		{
+			name:      "unknown preference",
+			ops:       `[{"op":"add","path":"/font~1size","value":12}]`,
+			wantField: "font/size",
+		},
+		{
+			name:      "unknown escaped preference",
+			ops:       `[{"op":"remove","path":"/the~0me"}]`,
+			wantField: "the~me",
+		},
+		{
+			name:        "unsupported op",
+			ops:         `[{"op":"merge","path":"/theme","value":"dark"}]`,
+			wantMessage: true,
+		},
+		{
+			name:        "missing value",
+			ops:         `[{"op":"replace","path":"/theme"}]`,
+			wantMessage: true,
+		},
+		{
+			name:        "missing from",
+			ops:         `[{"op":"copy","path":"/theme"}]`,
+			wantMessage: true,
+		},
+		{
+			name:        "nested path",
+			ops:         `[{"op":"replace","path":"/theme/name","value":"dark"}]`,
+			wantMessage: true,
+		},
+		{
+			name:        "root path",
+			ops:         `[{"op":"replace","path":"","value":{}}]`,
+			wantMessage: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ops []JSONPatchOperation
+			if err := json.Unmarshal([]byte(tt.ops), &ops); err != nil {
+				t.Fatal(err)
+			}
+			doc := map[string]interface{}{
+				"id":            float64(1),
+				"version":       float64(3),
+				"theme":         "dark",
+				"language":      "spanish",
+				"notifications": false,
+			}
+			touched := map[string]bool{}
+			removed := map[string]bool{}
+
+			var err error
+			for i, op := range ops {
+				if err = applyPatchOperation(doc, touched, removed, i, op); err != nil {
+					break
+				}
+			}
+
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			case tt.wantMessage:
+				var patchErr *PreferencePatchError
+				if !errors.As(err, &patchErr) {
+					t.Fatalf("error = %v, want PreferencePatchError", err)
+				}
+				return
+			case tt.wantField != "":
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) || validationErr.Fields[0].Field != tt.wantField {
+					t.Fatalf("error = %v, want ValidationError for %q", err, tt.wantField)
+				}
+				return
+			case err != nil:
+				t.Fatalf("error = %v", err)
+			}
+
+			for key, want := range tt.wantDoc {
+				if !reflect.DeepEqual(doc[key], want) {
+					t.Errorf("doc[%q] = %#v, want %#v", key, doc[key], want)
+				}
+			}
+			var set, unset []string
+			for key := range touched {
+				if removed[key] {
+					unset = append(unset, key)
+				} else {
+					set = append(set, key)
+				}
+			}
+			if !sameKeys(set, tt.wantSet) {
+				t.Errorf("set = %v, want %v", set, tt.wantSet)
+			}
+			if !sameKeys(unset, tt.wantUnset) {
+				t.Errorf("unset = %v, want %v", unset, tt.wantUnset)
+			}
+		})
+	}
+}
+
+// sameKeys membandingkan dua daftar key tanpa memperhatikan urutan
+func sameKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := map[string]int{}
+	for _, key := range a {
+		seen[key]++
+	}
+	for _, key := range b {
+		seen[key]--
+		if seen[key] < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,448 @@
+// services/preferences.go
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"main/config"
+	"main/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrPreferencesNotFound dikembalikan jika pengguna tidak memiliki baris preferensi
+	ErrPreferencesNotFound = errors.New("preferences not found")
+	// ErrPreferenceVersionNotFound dikembalikan jika versi yang diminta untuk dipulihkan tidak ada
+	ErrPreferenceVersionNotFound = errors.New("preference version not found")
+	// ErrPreferencePreconditionFailed dikembalikan jika If-Match tidak cocok dengan versi preferensi saat ini
+	ErrPreferencePreconditionFailed = errors.New("preferences have been modified")
+)
+
+// PreferenceChangeContext menjelaskan asal perubahan preferensi untuk dicatat di riwayat
+type PreferenceChangeContext struct {
+	Source  string // salah satu models.PreferenceSource*
+	ActorID uint   // pengguna yang melakukan perubahan; 0 untuk proses sistem
+	IfMatch string // nilai header If-Match (opsional); perubahan ditolak jika tidak cocok dengan ETag saat ini
+}
+
+// PreferenceETag mengembalikan ETag representasi preferensi, diturunkan dari versinya dan fingerprint registry.
+// Fingerprint membuat ETag berubah saat deploy menambah preferensi atau mengganti default, meskipun versinya sama.
+func PreferenceETag(preferences *models.UserPreferences) string {
+	return fmt.Sprintf(`"v%d-%s"`, preferences.Version, models.PreferenceRegistryFingerprint())
+}
+
+// MatchETag memeriksa apakah header If-Match/If-None-Match (daftar ETag dipisah koma atau "*") memuat etag.
+// Perbandingan weak mengabaikan prefiks W/ (untuk If-None-Match); perbandingan strong menolak ETag weak (untuk If-Match).
+func MatchETag(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// PreferenceHistoryPage berisi satu halaman riwayat perubahan preferensi, terbaru lebih dulu
+type PreferenceHistoryPage struct {
+	History  []models.PreferenceHistory `json:"history"`
+	Version  int64                      `json:"version"` // versi preferensi saat ini
+	Total    int64                      `json:"total"`
+	Page     int                        `json:"page"`
+	PageSize int                        `json:"page_size"`
+}
+
+// GetPreferences mengambil preferensi pengguna beserta nilai-nilainya
+func GetPreferences(userID uint) (*models.UserPreferences, error) {
+	return loadPreferences(config.DB, userID)
+}
+
+// ValidatePreferenceChanges memvalidasi perubahan terhadap registry preferensi dan mengembalikan nilainya
+// dalam tipe yang sudah dinormalisasi. Semua field yang tidak valid (key tidak terdaftar, null, tipe atau
+// nilai salah) dikumpulkan sekaligus dalam satu ValidationError.
+func ValidatePreferenceChanges(changes map[string]interface{}) (map[string]interface{}, error) {
+	normalized := make(map[string]interface{}, len(changes))
+	var failures []FieldError
+
+	for _, key := range sortedKeys(changes) {
+		value := changes[key]
+		def, ok := models.LookupPreference(key)
+		if !ok {
+			failures = append(failures, FieldError{Field: key, Code: "unknown_field", Message: "is not a known preference"})
+			continue
+		}
+		if value == nil {
+			failures = append(failures, FieldError{Field: key, Code: "invalid_type", Message: "must not be null"})
+			continue
+		}
+
+		normalizedValue, err := def.Normalize(value)
+		if err != nil {
+			failure := FieldError{Field: key, Code: "invalid_value", Message: err.Error()}
+			var valueErr *models.PreferenceValueError
+			if errors.As(err, &valueErr) {
+				failure.Code = valueErr.Code
+			}
+			failures = append(failures, failure)
+			continue
+		}
+		normalized[key] = normalizedValue
+	}
+
+	if len(failures) > 0 {
+		return nil, &ValidationError{Fields: failures}
+	}
+	return normalized, nil
+}
+
+// ApplyPreferenceChanges memvalidasi lalu menyimpan perubahan sebagian preferensi (key lain tidak berubah).
+// Dipakai oleh asisten, admin API dan POST /api/preferences; PATCH dan PUT memakai varian di
+// preference_patch.go. Semua jalur penulisan melewati updatePreferences agar validasinya sama dan
+// setiap perubahan tercatat di riwayat.
+func ApplyPreferenceChanges(userID uint, changes map[string]interface{}, change PreferenceChangeContext) (*models.UserPreferences, error) {
+	return updatePreferences(userID, change, func(*models.UserPreferences) (map[string]interface{}, []string, error) {
+		return changes, nil, nil
+	})
+}
+
+// preferenceUpdate menghitung perubahan dari preferensi saat ini (yang sudah dikunci): nilai baru untuk
+// key di set, dan key di unset yang dikembalikan ke default
+type preferenceUpdate func(current *models.UserPreferences) (set map[string]interface{}, unset []string, err error)
+
+// updatePreferences mengunci preferensi pengguna, menghitung perubahan dengan update, memvalidasinya
+// terhadap registry, lalu menyimpannya dalam satu transaksi
+func updatePreferences(userID uint, change PreferenceChangeContext, update preferenceUpdate) (*models.UserPreferences, error) {
+	var preferences *models.UserPreferences
+	var entries []models.PreferenceHistory
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		header, err := lockPreferences(tx, userID, change)
+		if err != nil {
+			return err
+		}
+
+		changes, unset, err := update(header)
+		if err != nil {
+			return err
+		}
+		set, err := validatePreferenceUpdate(changes, unset)
+		if err != nil {
+			return err
+		}
+		entries, err = writePreferenceValues(tx, header, set, unset, change)
+		if err != nil {
+			return err
+		}
+
+		preferences, err = loadPreferences(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Beri tahu klien yang berlangganan setelah transaksi berhasil
+	publishPreferenceChange(entries)
+	return preferences, nil
+}
+
+// validatePreferenceUpdate memvalidasi nilai baru dan key yang di-reset, lalu mengembalikan nilai baru
+// dalam bentuk JSON yang siap disimpan. Semua field yang tidak valid dilaporkan sekaligus.
+func validatePreferenceUpdate(changes map[string]interface{}, unset []string) (map[string]string, error) {
+	var failures []FieldError
+	normalized, err := ValidatePreferenceChanges(changes)
+	if err != nil {
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			return nil, err
+		}
+		failures = append(failures, validationErr.Fields...)
+	}
+	for _, key := range unset {
+		if _, ok := models.LookupPreference(key); !ok {
+			failures = append(failures, FieldError{Field: key, Code: "unknown_field", Message: "is not a known preference"})
+		}
+	}
+	if len(failures) > 0 {
+		sort.SliceStable(failures, func(i, j int) bool { return failures[i].Field < failures[j].Field })
+		return nil, &ValidationError{Fields: failures}
+	}
+
+	set := make(map[string]string, len(normalized))
+	for key, value := range normalized {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		set[key] = string(encoded)
+	}
+	return set, nil
+}
+
+// ListPreferenceHistory mengambil riwayat perubahan preferensi pengguna dengan paginasi,
+// opsional hanya untuk satu key
+func ListPreferenceHistory(userID uint, key string, page, pageSize int) (*PreferenceHistoryPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	preferences, err := loadPreferences(config.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	db := config.DB.Model(&models.PreferenceHistory{}).Where("user_id = ?", userID)
+	if key != "" {
+		db = db.Where("key = ?", key)
+	}
+
+	result := &PreferenceHistoryPage{History: []models.PreferenceHistory{}, Version: preferences.Version, Page: page, PageSize: pageSize}
+	if err := db.Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+	err = db.Order("version DESC, id").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&result.History).Error
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RestorePreferences mengembalikan semua preferensi ke keadaannya setelah versi tertentu (0 berarti
+// sebelum perubahan pertama yang tercatat). Pemulihan dicatat sebagai versi baru, sehingga bisa dibatalkan lagi.
+func RestorePreferences(userID uint, version int64, change PreferenceChangeContext) (*models.UserPreferences, error) {
+	var preferences *models.UserPreferences
+	var entries []models.PreferenceHistory
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		header, err := lockPreferences(tx, userID, change)
+		if err != nil {
+			return err
+		}
+
+		if version < 0 || version > header.Version {
+			return ErrPreferenceVersionNotFound
+		}
+
+		var history []models.PreferenceHistory
+		if err := tx.Where("user_id = ?", userID).Order("version, id").Find(&history).Error; err != nil {
+			return err
+		}
+
+		set := map[string]string{}
+		var unset []string
+		for key, target := range preferenceStateAt(history, version) {
+			def, ok := models.LookupPreference(key)
+			if !ok {
+				continue
+			}
+			if target == nil {
+				unset = append(unset, key)
+				continue
+			}
+			// Nilai lama yang tidak lagi valid terhadap definisinya dikembalikan ke default
+			value, err := def.Decode(*target)
+			if err != nil {
+				unset = append(unset, key)
+				continue
+			}
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			set[key] = string(encoded)
+		}
+
+		entries, err = writePreferenceValues(tx, header, set, unset, change)
+		if err != nil {
+			return err
+		}
+
+		preferences, err = loadPreferences(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Beri tahu klien yang berlangganan setelah transaksi berhasil
+	publishPreferenceChange(entries)
+	return preferences, nil
+}
+
+// preferenceStateAt menghitung nilai setiap key yang pernah berubah setelah versi tertentu dari riwayat
+// (urut naik). Key yang berubah sampai versi tersebut memakai nilai baru terakhirnya; key yang baru berubah
+// sesudahnya memakai nilai lama pada perubahan pertamanya. nil berarti belum diatur.
+func preferenceStateAt(history []models.PreferenceHistory, version int64) map[string]*string {
+	state := map[string]*string{}
+	changedAfter := map[string]bool{}
+	for _, entry := range history {
+		if entry.Version > version {
+			changedAfter[entry.Key] = true
+		}
+	}
+	for _, entry := range history {
+		if !changedAfter[entry.Key] {
+			continue
+		}
+		if entry.Version <= version {
+			state[entry.Key] = entry.NewValue
+		} else if _, seen := state[entry.Key]; !seen {
+			state[entry.Key] = entry.OldValue
+		}
+	}
+	return state
+}
+
+// lockPreferences mengunci baris preferensi (beserta nilai-nilainya) agar perubahan bersamaan untuk pengguna
+// yang sama diproses berurutan, lalu memeriksa If-Match terhadap versi yang terkunci
+func lockPreferences(tx *gorm.DB, userID uint, change PreferenceChangeContext) (*models.UserPreferences, error) {
+	var header models.UserPreferences
+	result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Values").Where("user_id = ?", userID).First(&header)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrPreferencesNotFound
+		}
+		return nil, result.Error
+	}
+	if change.IfMatch != "" && !MatchETag(change.IfMatch, PreferenceETag(&header), false) {
+		return nil, ErrPreferencePreconditionFailed
+	}
+	return &header, nil
+}
+
+// writePreferenceValues menyimpan nilai (JSON) di set dan menghapus key di unset, lalu mencatat setiap nilai
+// yang benar-benar berubah di riwayat dengan satu nomor versi baru. Pemanggil harus sudah mengunci header
+// dengan lockPreferences. Entri riwayat yang dibuat dikembalikan.
+func writePreferenceValues(tx *gorm.DB, header *models.UserPreferences, set map[string]string, unset []string, change PreferenceChangeContext) ([]models.PreferenceHistory, error) {
+	current := make(map[string]string, len(header.Values))
+	for _, value := range header.Values {
+		current[value.Key] = value.Value
+	}
+
+	now := time.Now()
+	var entries []models.PreferenceHistory
+	var rows []models.PreferenceValue
+	var removed []string
+	for _, key := range sortedStringKeys(set) {
+		newValue := set[key]
+		oldValue, exists := current[key]
+		if exists && oldValue == newValue {
+			continue
+		}
+		entry := models.PreferenceHistory{Key: key, NewValue: &newValue}
+		if exists {
+			entry.OldValue = &oldValue
+		}
+		entries = append(entries, entry)
+		rows = append(rows, models.PreferenceValue{
+			UserID:    header.UserID,
+			Key:       key,
+			Value:     newValue,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+	unset = append([]string(nil), unset...)
+	sort.Strings(unset)
+	for _, key := range unset {
+		oldValue, exists := current[key]
+		if !exists {
+			continue
+		}
+		entries = append(entries, models.PreferenceHistory{Key: key, OldValue: &oldValue})
+		removed = append(removed, key)
+	}
+
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	version := header.Version
+	var actorID *uint
+	if change.ActorID != 0 {
+		actorID = &change.ActorID
+	}
+	for i := range entries {
+		entries[i].UserID = header.UserID
+		entries[i].Version = version + 1
+		entries[i].Source = change.Source
+		entries[i].ActorID = actorID
+		entries[i].CreatedAt = now
+	}
+
+	if len(rows) > 0 {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+		}).Create(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(removed) > 0 {
+		if err := tx.Where("user_id = ? AND key IN ?", header.UserID, removed).Delete(&models.PreferenceValue{}).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Create(&entries).Error; err != nil {
+		return nil, err
+	}
+	header.Version = version + 1
+	if err := tx.Model(header).Updates(map[string]interface{}{"version": header.Version, "updated_at": now}).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// loadPreferences mengambil baris preferensi beserta nilai-nilainya
+func loadPreferences(db *gorm.DB, userID uint) (*models.UserPreferences, error) {
+	var preferences models.UserPreferences
+	result := db.Preload("Values").Where("user_id = ?", userID).First(&preferences)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrPreferencesNotFound
+		}
+		return nil, result.Error
+	}
+	return &preferences, nil
+}
+
+// sortedKeys mengembalikan key map secara berurutan agar hasil validasi dan query deterministik
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedStringKeys sama seperti sortedKeys untuk map bernilai string
+func sortedStringKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
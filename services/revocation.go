@@ -0,0 +1,221 @@
+// services/revocation.go
+package services
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"main/config"
+	"main/models"
+	"main/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RevocationStore menyimpan daftar access token yang dicabut di database
+// dengan cache di memori agar middleware tidak perlu query di setiap request
+type RevocationStore struct {
+	mu     sync.RWMutex
+	tokens map[string]revocationEntry // jti -> status
+	users  map[uint]userRevocationEntry
+}
+
+type revocationEntry struct {
+	revoked    bool
+	validUntil time.Time
+}
+
+type userRevocationEntry struct {
+	revokedBefore time.Time
+	validUntil    time.Time
+}
+
+// Revocations adalah revocation store global yang dipakai oleh middleware dan handler
+var Revocations = NewRevocationStore()
+
+// NewRevocationStore membuat revocation store baru
+func NewRevocationStore() *RevocationStore {
+	return &RevocationStore{
+		tokens: make(map[string]revocationEntry),
+		users:  make(map[uint]userRevocationEntry),
+	}
+}
+
+// cacheTTL adalah lama hasil "tidak dicabut" disimpan di cache sebelum dicek ulang ke database,
+// sehingga pencabutan dari instance lain tetap terlihat
+func (s *RevocationStore) cacheTTL() time.Duration {
+	return config.GetEnvDuration("REVOCATION_CACHE_TTL", 30*time.Second)
+}
+
+// RevokeToken mencabut satu access token berdasarkan jti
+func (s *RevocationStore) RevokeToken(jti string, userID uint, expiresAt time.Time) error {
+	revoked := models.RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+	if err := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&revoked).Error; err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.tokens[jti] = revocationEntry{revoked: true, validUntil: expiresAt}
+	s.mu.Unlock()
+	return nil
+}
+
+// RevokeSession mencabut semua access token yang terikat ke sesi (klaim sid).
+// Pencabutan disimpan di tabel yang sama dengan jti memakai kunci "sid:<id>"; entri boleh dihapus
+// setelah masa berlaku access token lewat karena token yang diterbitkan sebelumnya sudah kedaluwarsa.
+func (s *RevocationStore) RevokeSession(sessionID string, userID uint) error {
+	return s.RevokeToken(sessionRevocationKey(sessionID), userID, time.Now().Add(utils.AccessTokenTTL()))
+}
+
+// RevokeAllForUser mencabut semua access token pengguna yang diterbitkan sampai waktu sekarang.
+// Klaim iat berpresisi mikrodetik (lihat utils.JWTPrecision), jadi batasnya adalah mikrodetik setelah
+// waktu sekarang: token yang diterbitkan sebelum pencabutan selalu memiliki iat lebih kecil dari batas.
+func (s *RevocationStore) RevokeAllForUser(userID uint) error {
+	now := time.Now()
+	revokedBefore := now.Truncate(utils.JWTPrecision).Add(utils.JWTPrecision)
+	revocation := models.UserTokenRevocation{
+		UserID:        userID,
+		RevokedBefore: revokedBefore,
+		UpdatedAt:     now,
+	}
+	err := config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_before", "updated_at"}),
+	}).Create(&revocation).Error
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.users[userID] = userRevocationEntry{revokedBefore: revokedBefore, validUntil: now.Add(s.cacheTTL())}
+	s.mu.Unlock()
+	return nil
+}
+
+// IsRevoked memeriksa apakah access token sudah dicabut
+func (s *RevocationStore) IsRevoked(claims *utils.JWTClaim) (bool, error) {
+	revokedBefore, err := s.userRevokedBefore(claims.UserID)
+	if err != nil {
+		return false, err
+	}
+	if !revokedBefore.IsZero() && claims.IssuedAt != nil && claims.IssuedAt.Time.Before(revokedBefore) {
+		return true, nil
+	}
+
+	if claims.SessionID != "" {
+		revoked, err := s.tokenRevoked(sessionRevocationKey(claims.SessionID))
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	if claims.ID == "" {
+		return false, nil
+	}
+	return s.tokenRevoked(claims.ID)
+}
+
+// sessionRevocationKey membuat kunci pencabutan sesi; tidak bisa bentrok dengan jti (base64url tanpa ":")
+func sessionRevocationKey(sessionID string) string {
+	return "sid:" + sessionID
+}
+
+// Prune menghapus entri yang sudah kedaluwarsa dari database dan cache
+func (s *RevocationStore) Prune() error {
+	now := time.Now()
+	if err := config.DB.Where("expires_at < ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+		return err
+	}
+	// Setelah masa berlaku access token lewat, semua token yang terbit sebelum batas sudah kedaluwarsa
+	if err := config.DB.Where("revoked_before < ?", now.Add(-utils.AccessTokenTTL())).Delete(&models.UserTokenRevocation{}).Error; err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	for jti, entry := range s.tokens {
+		if now.After(entry.validUntil) {
+			delete(s.tokens, jti)
+		}
+	}
+	for userID, entry := range s.users {
+		if now.After(entry.validUntil) {
+			delete(s.users, userID)
+		}
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// StartJanitor menjalankan Prune secara berkala di background
+func (s *RevocationStore) StartJanitor(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.Prune(); err != nil {
+				log.Printf("Failed to prune revoked tokens: %v", err)
+			}
+		}
+	}()
+}
+
+// tokenRevoked memeriksa status jti di cache, lalu di database jika belum ada di cache
+func (s *RevocationStore) tokenRevoked(jti string) (bool, error) {
+	now := time.Now()
+
+	s.mu.RLock()
+	entry, ok := s.tokens[jti]
+	s.mu.RUnlock()
+	if ok && now.Before(entry.validUntil) {
+		return entry.revoked, nil
+	}
+
+	var revoked models.RevokedToken
+	result := config.DB.Where("jti = ?", jti).First(&revoked)
+	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return false, result.Error
+	}
+
+	entry = revocationEntry{revoked: false, validUntil: now.Add(s.cacheTTL())}
+	if result.Error == nil {
+		entry = revocationEntry{revoked: true, validUntil: revoked.ExpiresAt}
+	}
+
+	s.mu.Lock()
+	s.tokens[jti] = entry
+	s.mu.Unlock()
+	return entry.revoked, nil
+}
+
+// userRevokedBefore mengambil batas pencabutan massal milik pengguna (zero time jika tidak ada)
+func (s *RevocationStore) userRevokedBefore(userID uint) (time.Time, error) {
+	now := time.Now()
+
+	s.mu.RLock()
+	entry, ok := s.users[userID]
+	s.mu.RUnlock()
+	if ok && now.Before(entry.validUntil) {
+		return entry.revokedBefore, nil
+	}
+
+	var revocation models.UserTokenRevocation
+	result := config.DB.Where("user_id = ?", userID).First(&revocation)
+	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return time.Time{}, result.Error
+	}
+
+	entry = userRevocationEntry{revokedBefore: revocation.RevokedBefore, validUntil: now.Add(s.cacheTTL())}
+
+	s.mu.Lock()
+	s.users[userID] = entry
+	s.mu.Unlock()
+	return entry.revokedBefore, nil
+}
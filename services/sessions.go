@@ -0,0 +1,220 @@
+// services/sessions.go
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"main/config"
+	"main/models"
+	"main/utils"
+
+	"gorm.io/gorm"
+)
+
+// ErrSessionNotFound dikembalikan jika sesi tidak ada, sudah berakhir, atau bukan milik pengguna
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionMetadata berisi informasi perangkat yang dicatat saat sesi dibuat atau dipakai
+type SessionMetadata struct {
+	DeviceName string
+	UserAgent  string
+	IP         string
+}
+
+// sessionLastSeenResolution membatasi seberapa sering last_seen_at ditulis ke database
+func sessionLastSeenResolution() time.Duration {
+	return config.GetEnvDuration("SESSION_LAST_SEEN_RESOLUTION", time.Minute)
+}
+
+var (
+	sessionSeenMu sync.Mutex
+	sessionSeen   = map[string]time.Time{} // sid -> waktu terakhir last_seen_at ditulis
+)
+
+// ListSessions menampilkan sesi aktif milik pengguna, yang terakhir dipakai lebih dulu
+func ListSessions(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := config.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeSession mengakhiri satu sesi milik pengguna: refresh token-nya dicabut dan
+// access token yang terikat ke sesi itu langsung ditolak
+func RevokeSession(userID uint, sessionID string) error {
+	var session models.Session
+	result := config.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).First(&session)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrSessionNotFound
+		}
+		return result.Error
+	}
+	return RevokeRefreshFamily(session.ID)
+}
+
+// TouchSession memperbarui last_seen_at dan IP sesi, paling sering sekali per SESSION_LAST_SEEN_RESOLUTION
+// per sesi agar setiap request tidak menulis ke database
+func TouchSession(sessionID, ip string) {
+	if sessionID == "" {
+		return
+	}
+
+	now := time.Now()
+	sessionSeenMu.Lock()
+	last, ok := sessionSeen[sessionID]
+	if ok && now.Sub(last) < sessionLastSeenResolution() {
+		sessionSeenMu.Unlock()
+		return
+	}
+	sessionSeen[sessionID] = now
+
+	// Cegah map tumbuh tanpa batas
+	if len(sessionSeen) > 10000 {
+		for sid, seen := range sessionSeen {
+			if now.Sub(seen) > sessionLastSeenResolution() {
+				delete(sessionSeen, sid)
+			}
+		}
+	}
+	sessionSeenMu.Unlock()
+
+	err := config.DB.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{
+			"last_seen_at": now,
+			"ip":           truncate(ip, 64),
+		}).Error
+	if err != nil {
+		log.Printf("Failed to update session last seen: %v", err)
+	}
+}
+
+// createSession membuat sesi baru untuk login di satu perangkat
+func createSession(tx *gorm.DB, userID uint, meta SessionMetadata) (*models.Session, error) {
+	id, err := utils.GenerateRandomToken(24)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		ID:         id,
+		UserID:     userID,
+		DeviceName: sessionDeviceName(meta),
+		UserAgent:  truncate(meta.UserAgent, 512),
+		IP:         truncate(meta.IP, 64),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(RefreshTokenTTL()),
+	}
+	if err := tx.Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// extendSession memperbarui sesi setelah refresh token baru diterbitkan. Family refresh token yang
+// dibuat sebelum ada tabel sesi dibuatkan sesinya di sini agar tetap terlihat dan bisa dicabut.
+func extendSession(tx *gorm.DB, userID uint, sessionID string, meta SessionMetadata, expiresAt time.Time) error {
+	now := time.Now()
+	result := tx.Model(&models.Session{}).
+		Where("id = ?", sessionID).
+		Updates(map[string]interface{}{
+			"last_seen_at": now,
+			"expires_at":   expiresAt,
+			"ip":           truncate(meta.IP, 64),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	return tx.Create(&models.Session{
+		ID:         sessionID,
+		UserID:     userID,
+		DeviceName: sessionDeviceName(meta),
+		UserAgent:  truncate(meta.UserAgent, 512),
+		IP:         truncate(meta.IP, 64),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+	}).Error
+}
+
+// sessionDeviceName memakai nama perangkat dari klien, atau menebaknya dari user agent
+func sessionDeviceName(meta SessionMetadata) string {
+	if name := strings.TrimSpace(meta.DeviceName); name != "" {
+		return truncate(name, 100)
+	}
+	return truncate(deviceNameFromUserAgent(meta.UserAgent), 100)
+}
+
+// deviceNameFromUserAgent menyusun nama perangkat sederhana seperti "Chrome on macOS" dari user agent
+func deviceNameFromUserAgent(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser := ""
+	for _, candidate := range []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			browser = candidate.name
+			break
+		}
+	}
+
+	os := ""
+	for _, candidate := range []struct{ token, name string }{
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"CrOS", "ChromeOS"},
+		{"Mac OS X", "macOS"},
+		{"Windows", "Windows"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			os = candidate.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	}
+
+	// Klien non-browser (curl/8.0, python-requests/2.31): pakai nama produknya saja
+	return strings.SplitN(strings.SplitN(userAgent, " ", 2)[0], "/", 2)[0]
+}
+
+// truncate memotong string ke panjang maksimum kolom (dalam byte) tanpa memotong karakter UTF-8
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	for max > 0 && !utf8.RuneStart(value[max]) {
+		max--
+	}
+	return value[:max]
+}
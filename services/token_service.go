@@ -0,0 +1,225 @@
+// services/token_service.go
+package services
+
+import (
+	"errors"
+	"time"
+
+	"main/config"
+	"main/models"
+	"main/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidRefreshToken dikembalikan jika refresh token tidak dikenal, kedaluwarsa, atau sudah dicabut
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused dikembalikan jika refresh token yang sudah dipakai dikirim ulang
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	// ErrAccountDisabled dikembalikan jika akun pengguna dinonaktifkan oleh admin
+	ErrAccountDisabled = errors.New("account is disabled")
+)
+
+// TokenPair berisi access token dan refresh token yang diterbitkan untuk pengguna
+type TokenPair struct {
+	UserID                uint
+	AccessToken           string
+	AccessTokenExpiresAt  time.Time
+	RefreshToken          string
+	RefreshTokenExpiresAt time.Time
+}
+
+// RefreshTokenTTL mengembalikan masa berlaku refresh token (default 30 hari)
+func RefreshTokenTTL() time.Duration {
+	return config.GetEnvDuration("JWT_REFRESH_TOKEN_TTL", 30*24*time.Hour)
+}
+
+// IssueTokens membuat sesi baru untuk perangkat yang login lalu menerbitkan access token dan
+// refresh token di family baru (ID family sama dengan ID sesi)
+func IssueTokens(userID uint, meta SessionMetadata) (*TokenPair, error) {
+	var pair *TokenPair
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		session, err := createSession(tx, userID, meta)
+		if err != nil {
+			return err
+		}
+
+		pair, err = issueTokens(tx, userID, session.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// RotateRefreshToken menukar refresh token dengan pasangan token baru dan memperbarui sesi terkait.
+// Jika token yang sudah pernah ditukar dikirim ulang, seluruh family dicabut.
+func RotateRefreshToken(rawToken string, meta SessionMetadata) (*TokenPair, error) {
+	var token models.RefreshToken
+	result := config.DB.Where("token_hash = ?", utils.HashToken(rawToken)).First(&token)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, result.Error
+	}
+
+	if token.RevokedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Token yang sudah ditukar dipakai lagi: kemungkinan dicuri, cabut seluruh family
+	if token.UsedAt != nil {
+		if err := RevokeRefreshFamily(token.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	var pair *TokenPair
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Tandai token sebagai terpakai secara atomik agar dua permintaan paralel tidak sama-sama berhasil
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", token.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		var err error
+		pair, err = issueTokens(tx, token.UserID, token.FamilyID)
+		if err != nil {
+			return err
+		}
+		return extendSession(tx, token.UserID, token.FamilyID, meta, pair.RefreshTokenExpiresAt)
+	})
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if revokeErr := RevokeRefreshFamily(token.FamilyID); revokeErr != nil {
+			return nil, revokeErr
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return pair, nil
+}
+
+// RevokeRefreshFamily mencabut semua refresh token dalam satu family beserta sesinya,
+// termasuk access token yang sudah diterbitkan untuk sesi tersebut
+func RevokeRefreshFamily(familyID string) error {
+	now := time.Now()
+	if err := config.DB.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+
+	var session models.Session
+	result := config.DB.Where("id = ?", familyID).First(&session)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return result.Error
+	}
+	if err := config.DB.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return Revocations.RevokeSession(session.ID, session.UserID)
+}
+
+// RevokeUserRefreshTokens mencabut semua refresh token dan sesi milik pengguna
+func RevokeUserRefreshTokens(userID uint) error {
+	now := time.Now()
+	if err := config.DB.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return config.DB.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
+
+// RevokeRefreshTokenForUser mencabut family dari refresh token tertentu jika token tersebut milik pengguna
+func RevokeRefreshTokenForUser(rawToken string, userID uint) error {
+	var token models.RefreshToken
+	result := config.DB.Where("token_hash = ? AND user_id = ?", utils.HashToken(rawToken), userID).First(&token)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		return result.Error
+	}
+	return RevokeRefreshFamily(token.FamilyID)
+}
+
+// RevokeAllUserTokens mencabut semua access token dan refresh token milik pengguna (logout dari semua perangkat)
+func RevokeAllUserTokens(userID uint) error {
+	if err := Revocations.RevokeAllForUser(userID); err != nil {
+		return err
+	}
+	return RevokeUserRefreshTokens(userID)
+}
+
+// issueTokens membuat access token dan menyimpan refresh token baru di family (sesi) yang diberikan
+func issueTokens(tx *gorm.DB, userID uint, familyID string) (*TokenPair, error) {
+	// Klaim access token diambil dari data pengguna terbaru
+	var user models.User
+	if err := tx.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if user.Disabled {
+		return nil, ErrAccountDisabled
+	}
+
+	accessToken, accessExpiresAt, err := utils.GenerateJWT(utils.JWTClaim{
+		UserID:        user.ID,
+		EmailVerified: user.EmailVerified,
+		Role:          user.Role,
+		SessionID:     familyID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rawRefreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken := models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(rawRefreshToken),
+		ExpiresAt: time.Now().Add(RefreshTokenTTL()),
+		CreatedAt: time.Now(),
+	}
+	if err := tx.Create(&refreshToken).Error; err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		UserID:                userID,
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessExpiresAt,
+		RefreshToken:          rawRefreshToken,
+		RefreshTokenExpiresAt: refreshToken.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,267 @@
+// services/two_factor.go
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"main/config"
+	"main/models"
+	"main/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrTOTPAlreadyEnabled dikembalikan jika pengguna mencoba setup ulang saat 2FA sudah aktif
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTOTPNotEnabled dikembalikan jika 2FA belum aktif atau belum di-setup
+	ErrTOTPNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrTOTPSetupRequired dikembalikan jika verifikasi dilakukan sebelum setup
+	ErrTOTPSetupRequired = errors.New("two-factor setup has not been started")
+	// ErrInvalidSecondFactor dikembalikan jika kode TOTP atau kode pemulihan tidak valid
+	ErrInvalidSecondFactor = errors.New("invalid authentication code")
+	// ErrInvalidMFAToken dikembalikan jika token "mfa pending" tidak valid atau kedaluwarsa
+	ErrInvalidMFAToken = errors.New("invalid or expired mfa token")
+)
+
+// recoveryCodeAlphabet tidak memuat karakter yang mudah tertukar (0/o, 1/l/i)
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// recoveryCodeCount adalah jumlah kode pemulihan yang dibuat setiap kali 2FA diaktifkan
+const recoveryCodeCount = 10
+
+// MFAPendingTTL mengembalikan masa berlaku token "mfa pending" di antara langkah login (default 5 menit)
+func MFAPendingTTL() time.Duration {
+	return config.GetEnvDuration("MFA_PENDING_TTL", 5*time.Minute)
+}
+
+// SetupTOTP membuat secret TOTP baru untuk pengguna; 2FA baru aktif setelah VerifyTOTPSetup berhasil
+func SetupTOTP(userID uint) (secret string, provisioningURI string, err error) {
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return "", "", err
+	}
+	if user.TOTPEnabled {
+		return "", "", ErrTOTPAlreadyEnabled
+	}
+
+	secret, err = utils.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	err = config.DB.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"totp_secret":       secret,
+		"totp_last_counter": 0,
+	}).Error
+	if err != nil {
+		return "", "", err
+	}
+
+	issuer := config.GetEnv("TOTP_ISSUER", "User Preferences")
+	return secret, utils.TOTPProvisioningURI(issuer, user.Username, secret), nil
+}
+
+// VerifyTOTPSetup mengaktifkan 2FA setelah pengguna membuktikan authenticator-nya menghasilkan kode yang benar,
+// lalu mengembalikan kode pemulihan baru (hanya ditampilkan sekali)
+func VerifyTOTPSetup(userID uint, code string) ([]string, error) {
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTOTPSetupRequired
+	}
+
+	counter, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidSecondFactor
+	}
+
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_enabled":      true,
+			"totp_last_counter": counter,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP menonaktifkan 2FA setelah password dan kode (TOTP atau pemulihan) dikonfirmasi
+func DisableTOTP(userID uint, password, code string) error {
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrTOTPNotEnabled
+	}
+	if !user.CheckPassword(password) {
+		return ErrInvalidSecondFactor
+	}
+	if err := VerifySecondFactor(userID, code); err != nil {
+		return err
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_enabled":      false,
+			"totp_secret":       "",
+			"totp_last_counter": 0,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// VerifySecondFactor memeriksa kode TOTP (tanpa boleh dipakai ulang) atau kode pemulihan sekali pakai
+func VerifySecondFactor(userID uint, code string) error {
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrTOTPNotEnabled
+	}
+
+	if counter, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now()); ok {
+		// Update atomik: kode untuk langkah waktu yang sama atau lebih lama tidak bisa dipakai lagi
+		result := config.DB.Model(&models.User{}).
+			Where("id = ? AND totp_last_counter < ?", userID, counter).
+			Update("totp_last_counter", counter)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidSecondFactor
+		}
+		return nil
+	}
+
+	// Bukan kode TOTP, coba sebagai kode pemulihan
+	result := config.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidSecondFactor
+	}
+	return nil
+}
+
+// CreateMFAPendingToken membuat token berumur pendek yang hanya bisa ditukar di endpoint challenge 2FA
+func CreateMFAPendingToken(userID uint) (string, time.Time, error) {
+	ttl := MFAPendingTTL()
+	token, err := utils.GeneratePurposeToken(utils.PurposeMFAPending, userID, "", ttl)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, time.Now().Add(ttl), nil
+}
+
+// CompleteMFAChallenge menukar token "mfa pending" dan kode 2FA yang valid dengan pasangan token sesi penuh
+func CompleteMFAChallenge(mfaToken, code string, meta SessionMetadata) (*TokenPair, error) {
+	claims, err := utils.ValidatePurposeToken(mfaToken, utils.PurposeMFAPending)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	// Batasi tebakan kode 6 digit dengan limiter yang sama seperti login
+	limiter := DefaultLoginLimiter()
+	key := MFAKey(claims.UserID)
+	wait, err := limiter.Attempt(key)
+	if err != nil {
+		return nil, err
+	}
+	if wait > 0 {
+		return nil, &RateLimitError{RetryAfter: wait}
+	}
+
+	if err := VerifySecondFactor(claims.UserID, code); err != nil {
+		if errors.Is(err, ErrInvalidSecondFactor) {
+			if _, failErr := limiter.Failed(key); failErr != nil {
+				return nil, failErr
+			}
+		} else if releaseErr := limiter.Release(key); releaseErr != nil {
+			return nil, releaseErr
+		}
+		return nil, err
+	}
+
+	if err := limiter.Succeed(key); err != nil {
+		return nil, err
+	}
+	return IssueTokens(claims.UserID, meta)
+}
+
+// replaceRecoveryCodes menghapus kode pemulihan lama dan membuat set baru
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, models.RecoveryCode{
+			UserID:    userID,
+			CodeHash:  hashRecoveryCode(code),
+			CreatedAt: time.Now(),
+		})
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode membuat kode pemulihan dengan format xxxxx-xxxxx
+func generateRecoveryCode() (string, error) {
+	var sb strings.Builder
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			sb.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(recoveryCodeAlphabet[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// hashRecoveryCode menormalkan kode (huruf kecil, tanpa tanda hubung/spasi) lalu menghitung hash-nya
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return utils.HashToken(normalized)
+}
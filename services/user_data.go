@@ -0,0 +1,125 @@
+// services/user_data.go
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"main/models"
+	"main/utils"
+
+	"gorm.io/gorm"
+)
+
+// userOwnedModels adalah semua tabel yang menyimpan data milik pengguna (kolom user_id).
+// Tambahkan model baru di sini agar ikut terhapus saat akun dihapus permanen.
+// Daftar pencabutan token (revoked_tokens, user_token_revocations) sengaja tidak dihapus di sini:
+// entri tersebut harus bertahan sampai access token terkait kedaluwarsa dan dibersihkan oleh janitor.
+var userOwnedModels = []interface{}{
+	&models.RefreshToken{},
+	&models.Session{},
+	&models.DataExport{},
+	&models.PasswordResetToken{},
+	&models.RecoveryCode{},
+	&models.APIToken{},
+	&models.ExternalIdentity{},
+	&models.OIDCAuthRequest{},
+	&models.WebAuthnCredential{},
+	&models.WebAuthnChallenge{},
+	&models.PreferenceHistory{},
+	&models.PreferenceValue{},
+	&models.UserPreferences{},
+}
+
+// purgeUserData menghapus permanen pengguna beserta semua data turunannya di dalam transaksi tx
+func purgeUserData(tx *gorm.DB, userID uint) error {
+	for _, model := range userOwnedModels {
+		if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Unscoped().Delete(&models.User{}, userID).Error
+}
+
+// FindUserByIdentifier mencari pengguna berdasarkan username atau email tanpa membedakan huruf besar/kecil
+// (dibandingkan dalam bentuk kanonik). Identifier yang memuat "@" dicari sebagai email lebih dulu.
+// Mengembalikan ErrUserNotFound jika tidak ada yang cocok.
+func FindUserByIdentifier(db *gorm.DB, identifier string) (*models.User, error) {
+	canonical := utils.CanonicalIdentifier(identifier)
+	if canonical == "" {
+		return nil, ErrUserNotFound
+	}
+
+	columns := []string{"username_canonical", "email_canonical"}
+	if strings.Contains(canonical, "@") {
+		columns = []string{"email_canonical", "username_canonical"}
+	}
+	for _, column := range columns {
+		var user models.User
+		err := db.Session(&gorm.Session{}).Where(column+" = ?", canonical).First(&user).Error
+		if err == nil {
+			return &user, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+// createUserWithPreferences membuat pengguna baru beserta preferensi default-nya di dalam transaksi tx
+func createUserWithPreferences(tx *gorm.DB, user *models.User) error {
+	if err := tx.Create(user).Error; err != nil {
+		return err
+	}
+
+	preferences := models.DefaultPreferences(user.ID)
+	if err := tx.Create(&preferences).Error; err != nil {
+		return err
+	}
+	user.Preferences = preferences
+	return nil
+}
+
+var usernameDisallowed = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// availableUsername menurunkan username untuk akun yang dibuat otomatis (dari preferred, lalu bagian lokal email)
+// dan menambahkan angka acak jika sudah dipakai
+func availableUsername(tx *gorm.DB, preferred, email string) (string, error) {
+	base := preferred
+	if at := strings.Index(base, "@"); at > 0 {
+		base = base[:at]
+	}
+	if base == "" {
+		base = strings.SplitN(email, "@", 2)[0]
+	}
+	base = strings.Trim(usernameDisallowed.ReplaceAllString(strings.ToLower(base), ""), "._-")
+	if base == "" {
+		base = "user"
+	}
+	if len(base) > 90 {
+		base = base[:90]
+	}
+
+	candidate := base
+	for i := 0; i < 10; i++ {
+		var count int64
+		if err := tx.Unscoped().Model(&models.User{}).Where("username_canonical = ?", utils.CanonicalIdentifier(candidate)).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+
+		suffix, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s%04d", base, suffix.Int64())
+	}
+	return "", fmt.Errorf("could not find a free username for %q", base)
+}
@@ -0,0 +1,25 @@
+// services/validation.go
+package services
+
+import "strings"
+
+// FieldError menjelaskan satu aturan validasi yang gagal untuk satu field request
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError berisi semua field error dari satu request; handler mengirimnya sebagai 422
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Error menggabungkan pesan semua field error
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Field+": "+field.Message)
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
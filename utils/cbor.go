@@ -0,0 +1,197 @@
+// utils/cbor.go
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// cborMaxDepth membatasi kedalaman struktur bersarang agar input berbahaya tidak menghabiskan stack
+const cborMaxDepth = 16
+
+// ErrInvalidCBOR dikembalikan jika data bukan CBOR yang valid atau memakai fitur yang tidak didukung
+var ErrInvalidCBOR = errors.New("invalid cbor")
+
+// DecodeCBOR mendekode satu item CBOR (RFC 8949) di awal data dan mengembalikan sisa byte setelahnya.
+//
+// Dekoder ini hanya mendukung subset yang dipakai WebAuthn (CTAP2 canonical CBOR): panjang definit saja.
+// Hasilnya memakai tipe Go berikut: bilangan bulat menjadi int64, byte string menjadi []byte, text string
+// menjadi string, array menjadi []interface{}, map menjadi map[interface{}]interface{}, serta bool, nil
+// dan float64. Tag diabaikan dan hanya isinya yang dikembalikan.
+func DecodeCBOR(data []byte) (interface{}, []byte, error) {
+	d := cborDecoder{data: data}
+	value, err := d.decode(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return value, d.data[d.pos:], nil
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > cborMaxDepth {
+		return nil, fmt.Errorf("%w: nesting too deep", ErrInvalidCBOR)
+	}
+	if d.pos >= len(d.data) {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrInvalidCBOR)
+	}
+
+	initial := d.data[d.pos]
+	d.pos++
+	major := initial >> 5
+	info := initial & 0x1f
+
+	// Tipe mayor 7 (float dan simple value) membaca argumennya sendiri
+	if major == 7 {
+		return d.decodeSimple(info)
+	}
+
+	arg, err := d.readArgument(info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case 0: // unsigned integer
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: integer overflow", ErrInvalidCBOR)
+		}
+		return int64(arg), nil
+	case 1: // negative integer: -1 - arg
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: integer overflow", ErrInvalidCBOR)
+		}
+		return -1 - int64(arg), nil
+	case 2: // byte string
+		b, err := d.readBytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case 3: // text string
+		b, err := d.readBytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case 4: // array
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, fmt.Errorf("%w: array length exceeds data", ErrInvalidCBOR)
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case 5: // map
+		if arg > uint64(len(d.data)-d.pos)/2 {
+			return nil, fmt.Errorf("%w: map length exceeds data", ErrInvalidCBOR)
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, fmt.Errorf("%w: unsupported map key type %T", ErrInvalidCBOR, key)
+			}
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := m[key]; exists {
+				return nil, fmt.Errorf("%w: duplicate map key %v", ErrInvalidCBOR, key)
+			}
+			m[key] = value
+		}
+		return m, nil
+	case 6: // tag: abaikan nomor tag, kembalikan isinya
+		return d.decode(depth + 1)
+	}
+	return nil, fmt.Errorf("%w: unknown major type %d", ErrInvalidCBOR, major)
+}
+
+// readArgument membaca argumen (nilai atau panjang) setelah byte awal
+func (d *cborDecoder) readArgument(info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info == 24:
+		b, err := d.readBytes(1)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(b[0]), nil
+	case info == 25:
+		b, err := d.readBytes(2)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case info == 26:
+		b, err := d.readBytes(4)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	case info == 27:
+		b, err := d.readBytes(8)
+		if err != nil {
+			return 0, err
+		}
+		return binary.BigEndian.Uint64(b), nil
+	case info == 31:
+		return 0, fmt.Errorf("%w: indefinite lengths are not supported", ErrInvalidCBOR)
+	default:
+		return 0, fmt.Errorf("%w: reserved additional information %d", ErrInvalidCBOR, info)
+	}
+}
+
+// decodeSimple mendekode tipe mayor 7: false, true, null, undefined dan float
+func (d *cborDecoder) decodeSimple(info byte) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 26:
+		b, err := d.readBytes(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 27:
+		b, err := d.readBytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported simple value %d", ErrInvalidCBOR, info)
+	}
+}
+
+// readBytes membaca n byte berikutnya tanpa melewati batas data
+func (d *cborDecoder) readBytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrInvalidCBOR)
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
@@ -0,0 +1,85 @@
+// utils/cbor_test.go
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  interface{}
+		rest  []byte
+	}{
+		{"small unsigned", []byte{0x17}, int64(23), []byte{}},
+		{"one-byte unsigned", []byte{0x18, 0x18}, int64(24), []byte{}},
+		{"two-byte unsigned", []byte{0x19, 0x01, 0x00}, int64(256), []byte{}},
+		{"negative", []byte{0x20}, int64(-1), []byte{}},
+		{"one-byte negative", []byte{0x38, 0x63}, int64(-100), []byte{}},
+		{"byte string", []byte{0x43, 0x01, 0x02, 0x03}, []byte{0x01, 0x02, 0x03}, []byte{}},
+		{"text string", []byte{0x63, 'a', 'b', 'c'}, "abc", []byte{}},
+		{"array", []byte{0x82, 0x01, 0x02}, []interface{}{int64(1), int64(2)}, []byte{}},
+		{"map", []byte{0xa2, 0x01, 0x02, 0x61, 'a', 0x03}, map[interface{}]interface{}{int64(1): int64(2), "a": int64(3)}, []byte{}},
+		{"false", []byte{0xf4}, false, []byte{}},
+		{"true", []byte{0xf5}, true, []byte{}},
+		{"null", []byte{0xf6}, nil, []byte{}},
+		{"float64", []byte{0xfb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}, 1.5, []byte{}},
+		{"tag is skipped", []byte{0xc2, 0x41, 0x01}, []byte{0x01}, []byte{}},
+		{"remaining bytes are returned", []byte{0x01, 0x02, 0x03}, int64(1), []byte{0x02, 0x03}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, err := DecodeCBOR(tt.input)
+			if err != nil {
+				t.Fatalf("DecodeCBOR(%x) error = %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeCBOR(%x) = %#v, want %#v", tt.input, got, tt.want)
+			}
+			if !bytes.Equal(rest, tt.rest) {
+				t.Errorf("DecodeCBOR(%x) rest = %x, want %x", tt.input, rest, tt.rest)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORInvalid(t *testing.T) {
+	deep := append(bytes.Repeat([]byte{0x81}, cborMaxDepth+2), 0x00)
+
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"empty", nil},
+		{"truncated argument", []byte{0x19, 0x01}},
+		{"truncated byte string", []byte{0x43, 0x01, 0x02}},
+		{"truncated text string", []byte{0x62, 'a'}},
+		{"truncated array", []byte{0x82, 0x01}},
+		{"truncated map value", []byte{0xa1, 0x01}},
+		{"truncated float", []byte{0xfa, 0x00, 0x00}},
+		{"array length exceeds data", []byte{0x9a, 0xff, 0xff, 0xff, 0xff}},
+		{"map length exceeds data", []byte{0xba, 0xff, 0xff, 0xff, 0xff, 0x00}},
+		{"indefinite length", []byte{0x9f, 0x01, 0xff}},
+		{"reserved additional information", []byte{0x1c}},
+		{"unsigned overflow", []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"negative overflow", []byte{0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"duplicate integer map key", []byte{0xa2, 0x01, 0x02, 0x01, 0x03}},
+		{"duplicate text map key", []byte{0xa2, 0x61, 'a', 0x01, 0x61, 'a', 0x02}},
+		{"byte string map key", []byte{0xa1, 0x41, 0x00, 0x01}},
+		{"unsupported simple value", []byte{0xf8, 0x20}},
+		{"nesting too deep", deep},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := DecodeCBOR(tt.input); !errors.Is(err, ErrInvalidCBOR) {
+				t.Errorf("DecodeCBOR(%x) error = %v, want ErrInvalidCBOR", tt.input, err)
+			}
+		})
+	}
+}
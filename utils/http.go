@@ -0,0 +1,28 @@
+// utils/http.go
+package utils
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ClientIP mengembalikan alamat IP klien. Header X-Forwarded-For / X-Real-IP hanya dipercaya
+// jika TRUST_PROXY_HEADERS=true (server berada di belakang reverse proxy tepercaya).
+func ClientIP(r *http.Request) string {
+	if trust, _ := strconv.ParseBool(getEnv("TRUST_PROXY_HEADERS", "false")); trust {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+		if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+			return strings.TrimSpace(realIP)
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,15 @@
+// utils/identity.go
+package utils
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// CanonicalIdentifier mengubah username atau email ke bentuk kanonik untuk pencarian dan keunikan:
+// spasi di ujung dibuang, Unicode dinormalisasi dengan NFKC (mis. huruf lebar penuh menjadi ASCII), lalu huruf kecil.
+// Nilai aslinya tetap disimpan untuk ditampilkan.
+func CanonicalIdentifier(value string) string {
+	return strings.ToLower(norm.NFKC.String(strings.TrimSpace(value)))
+}
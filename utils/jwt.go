@@ -2,62 +2,131 @@
 package utils
 
 import (
+	"fmt"
 	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
-// JWTClaim adalah struktur klaim dalam JWT token
+// TokenUseAccess menandai JWT sebagai access token; token dengan tujuan lain
+// (verifikasi email, dll) ditolak oleh ValidateToken
+const TokenUseAccess = "access"
+
+// JWTPrecision adalah presisi klaim waktu (iat, exp, nbf) pada token yang diterbitkan.
+// Presisi mikrodetik (sama dengan kolom timestamp Postgres) membuat pencabutan semua token
+// pengguna tepat tanpa harus menunggu pergantian detik sebelum menerbitkan token baru.
+const JWTPrecision = time.Microsecond
+
+func init() {
+	jwt.TimePrecision = JWTPrecision
+}
+
+// JWTClaim adalah struktur klaim dalam JWT token.
+// Klaim jti (RegisteredClaims.ID) dipakai untuk mencabut token sebelum kedaluwarsa,
+// sedangkan sid mengikat token ke sesi (perangkat) tempat token diterbitkan.
 type JWTClaim struct {
-	UserID uint `json:"user_id"`
+	UserID        uint   `json:"user_id"`
+	TokenUse      string `json:"token_use"`
+	EmailVerified bool   `json:"email_verified"`
+	Role          string `json:"role"`
+	SessionID     string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT membuat JWT token baru untuk pengguna
-func GenerateJWT(userID uint) (string, error) {
-	// Mendapatkan secret key dari environment variable
-	secretKey := getEnv("JWT_SECRET_KEY", "your-secret-key")
-
-	claims := JWTClaim{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // Token berlaku 24 jam
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-		},
+// AccessTokenTTL mengembalikan masa berlaku access token (default 15 menit)
+func AccessTokenTTL() time.Duration {
+	ttl, err := time.ParseDuration(getEnv("JWT_ACCESS_TOKEN_TTL", "15m"))
+	if err != nil || ttl <= 0 {
+		return 15 * time.Minute
 	}
+	return ttl
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(secretKey))
+// GenerateJWT membuat access token JWT berumur pendek dari klaim pengguna (UserID, EmailVerified, Role, SessionID).
+// Klaim standar (jti, exp, iat, nbf) diisi oleh fungsi ini.
+func GenerateJWT(claims JWTClaim) (string, time.Time, error) {
+	// ID unik token (jti) agar token bisa dicabut satu per satu
+	jti, err := GenerateRandomToken(16)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
-	return tokenString, nil
+	now := time.Now()
+	expiresAt := now.Add(AccessTokenTTL())
+
+	claims.TokenUse = TokenUseAccess
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        jti,
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+	}
+
+	tokenString, err := signClaims(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, expiresAt, nil
 }
 
 // ValidateToken memvalidasi token JWT
 func ValidateToken(tokenString string) (*JWTClaim, error) {
-	secretKey := getEnv("JWT_SECRET_KEY", "your-secret-key")
+	claims := &JWTClaim{}
+	if err := parseClaims(tokenString, claims); err != nil {
+		return nil, err
+	}
+	if claims.TokenUse != TokenUseAccess {
+		return nil, fmt.Errorf("token is not an access token")
+	}
+	return claims, nil
+}
+
+// signClaims menandatangani klaim dengan kunci aktif dan mencantumkan kid di header token
+func signClaims(claims jwt.Claims) (string, error) {
+	keys, err := LoadKeySet()
+	if err != nil {
+		return "", err
+	}
+	key := keys.ActiveKey()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.KID
+	return token.SignedString(key.Private)
+}
+
+// parseClaims memverifikasi token dengan kunci yang sesuai kid-nya dan mengisi klaim
+func parseClaims(tokenString string, claims jwt.Claims) error {
+	keys, err := LoadKeySet()
+	if err != nil {
+		return err
+	}
 
 	token, err := jwt.ParseWithClaims(
 		tokenString,
-		&JWTClaim{},
+		claims,
 		func(token *jwt.Token) (interface{}, error) {
-			return []byte(secretKey), nil
+			kid, _ := token.Header["kid"].(string)
+			key, err := keys.VerificationKey(kid)
+			if err != nil {
+				return nil, err
+			}
+			// Algoritma di header harus sama dengan algoritma kunci (mencegah algorithm confusion)
+			if token.Method.Alg() != key.Method.Alg() {
+				return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+			}
+			return key.Public(), nil
 		},
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	claims, ok := token.Claims.(*JWTClaim)
-	if !ok || !token.Valid {
-		return nil, jwt.ErrSignatureInvalid
+	if !token.Valid {
+		return jwt.ErrSignatureInvalid
 	}
-
-	return claims, nil
+	return nil
 }
 
 // getEnv mendapatkan nilai dari environment variable atau menggunakan nilai default
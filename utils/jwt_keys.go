@@ -0,0 +1,290 @@
+// utils/jwt_keys.go
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// SigningKey adalah satu kunci penandatangan JWT yang diidentifikasi dengan kid
+type SigningKey struct {
+	KID     string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Retired bool // kunci yang sudah pensiun tidak dipakai lagi untuk verifikasi
+}
+
+// Public mengembalikan kunci publik dari SigningKey
+func (k *SigningKey) Public() crypto.PublicKey {
+	return k.Private.Public()
+}
+
+// KeySet berisi semua kunci JWT yang dikenal dan kid dari kunci aktif
+type KeySet struct {
+	Keys      map[string]*SigningKey
+	ActiveKID string
+}
+
+// JWK merupakan representasi JSON Web Key publik (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // kurva OKP/EC
+	X   string `json:"x,omitempty"`   // kunci publik OKP atau koordinat x EC
+	Y   string `json:"y,omitempty"`   // koordinat y EC
+}
+
+// JWKS merupakan kumpulan JWK untuk endpoint /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+var (
+	keySetOnce sync.Once
+	keySet     *KeySet
+	keySetErr  error
+)
+
+// LoadKeySet memuat kunci JWT sekali saja dari direktori JWT_KEYS_DIR.
+//
+// Setiap file <kid>.pem berisi private key PKCS#8 (RSA atau Ed25519) atau PKCS#1 (RSA).
+// JWT_ACTIVE_KID menentukan kunci untuk menandatangani token baru, sedangkan
+// JWT_RETIRED_KIDS (dipisahkan koma) berisi kunci yang tidak lagi diterima.
+// Jika JWT_KEYS_DIR kosong, kunci Ed25519 sementara dibuat untuk pengembangan lokal.
+func LoadKeySet() (*KeySet, error) {
+	keySetOnce.Do(func() {
+		keysDir := getEnv("JWT_KEYS_DIR", "")
+		if keysDir == "" {
+			log.Println("JWT_KEYS_DIR is not set, generating an ephemeral Ed25519 signing key (development only)")
+			keySet, keySetErr = ephemeralKeySet()
+			return
+		}
+		keySet, keySetErr = loadKeySetFromDir(keysDir, getEnv("JWT_ACTIVE_KID", ""), getEnv("JWT_RETIRED_KIDS", ""))
+	})
+	return keySet, keySetErr
+}
+
+// ActiveKey mengembalikan kunci yang dipakai untuk menandatangani token baru
+func (ks *KeySet) ActiveKey() *SigningKey {
+	return ks.Keys[ks.ActiveKID]
+}
+
+// VerificationKey mencari kunci berdasarkan kid dan memastikan kunci tersebut belum pensiun
+func (ks *KeySet) VerificationKey(kid string) (*SigningKey, error) {
+	key, ok := ks.Keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if key.Retired {
+		return nil, fmt.Errorf("signing key %q has been retired", kid)
+	}
+	return key, nil
+}
+
+// PublicJWKS mengembalikan kunci publik dari semua kunci yang belum pensiun
+func (ks *KeySet) PublicJWKS() JWKS {
+	kids := make([]string, 0, len(ks.Keys))
+	for kid, key := range ks.Keys {
+		if !key.Retired {
+			kids = append(kids, kid)
+		}
+	}
+	sort.Strings(kids)
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, kid := range kids {
+		key := ks.Keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+// PublicKey mengubah JWK publik (RSA, EC P-256/P-384/P-521, atau OKP Ed25519) menjadi kunci publik Go
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: invalid modulus: %w", j.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: invalid exponent: %w", j.Kid, err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("jwk %q: invalid RSA key", j.Kid)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("jwk %q: unsupported curve %q", j.Kid, j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: invalid x coordinate: %w", j.Kid, err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: invalid y coordinate: %w", j.Kid, err)
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("jwk %q: point is not on curve", j.Kid)
+		}
+		return key, nil
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("jwk %q: unsupported curve %q", j.Kid, j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("jwk %q: invalid Ed25519 key", j.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("jwk %q: unsupported key type %q", j.Kid, j.Kty)
+	}
+}
+
+// loadKeySetFromDir membaca semua file <kid>.pem dari direktori
+func loadKeySetFromDir(dir, activeKID, retiredKIDs string) (*KeySet, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	ks := &KeySet{Keys: make(map[string]*SigningKey), ActiveKID: activeKID}
+	for _, file := range files {
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		key, err := loadSigningKey(file, kid)
+		if err != nil {
+			return nil, err
+		}
+		ks.Keys[kid] = key
+	}
+
+	for _, kid := range strings.Split(retiredKIDs, ",") {
+		kid = strings.TrimSpace(kid)
+		if key, ok := ks.Keys[kid]; ok {
+			key.Retired = true
+		}
+	}
+
+	// Tanpa JWT_ACTIVE_KID, kid terbesar yang belum pensiun (misalnya tanggal terbaru) dijadikan aktif
+	if ks.ActiveKID == "" {
+		kids := make([]string, 0, len(ks.Keys))
+		for kid, key := range ks.Keys {
+			if !key.Retired {
+				kids = append(kids, kid)
+			}
+		}
+		sort.Strings(kids)
+		if len(kids) > 0 {
+			ks.ActiveKID = kids[len(kids)-1]
+		}
+	}
+
+	active, ok := ks.Keys[ks.ActiveKID]
+	if !ok {
+		return nil, fmt.Errorf("active signing key %q not found in %s", ks.ActiveKID, dir)
+	}
+	if active.Retired {
+		return nil, fmt.Errorf("active signing key %q is listed as retired", ks.ActiveKID)
+	}
+
+	return ks, nil
+}
+
+// loadSigningKey membaca private key dari file PEM dan menentukan algoritma tanda tangannya
+func loadSigningKey(file, kid string) (*SigningKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block found", file)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &SigningKey{KID: kid, Method: jwt.SigningMethodRS256, Private: key}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{KID: kid, Method: jwt.SigningMethodEdDSA, Private: key}, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported key type %T (expected RSA or Ed25519)", file, parsed)
+	}
+}
+
+// ephemeralKeySet membuat satu kunci Ed25519 yang hanya hidup selama proses berjalan
+func ephemeralKeySet() (*KeySet, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := GenerateRandomToken(8)
+	if err != nil {
+		return nil, err
+	}
+	kid = "dev-" + kid
+
+	return &KeySet{
+		Keys:      map[string]*SigningKey{kid: {KID: kid, Method: jwt.SigningMethodEdDSA, Private: private}},
+		ActiveKID: kid,
+	}, nil
+}
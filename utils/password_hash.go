@@ -0,0 +1,243 @@
+// utils/password_hash.go
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnknownPasswordHash dikembalikan jika format hash password tidak dikenali oleh hasher mana pun
+var ErrUnknownPasswordHash = errors.New("unknown password hash format")
+
+// PasswordHasher adalah antarmuka algoritma hash password sehingga algoritmanya bisa diganti
+// tanpa memutus hash yang sudah tersimpan
+type PasswordHasher interface {
+	// Hash meng-hash password dengan parameter hasher saat ini
+	Hash(password string) (string, error)
+	// Identifies memeriksa apakah hash tersimpan dibuat oleh algoritma hasher ini
+	Identifies(encoded string) bool
+	// Verify mencocokkan password dengan hash tersimpan (memakai parameter yang tercatat di hash)
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash memeriksa apakah hash tersimpan memakai parameter yang lebih lemah dari parameter saat ini
+	NeedsRehash(encoded string) bool
+}
+
+// Argon2idHasher meng-hash password dengan Argon2id (RFC 9106) dan menyimpannya sebagai string PHC:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+type Argon2idHasher struct {
+	Memory      uint32 // dalam KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// argon2idParams adalah parameter yang dibaca dari string PHC
+type argon2idParams struct {
+	memory, iterations uint32
+	parallelism        uint8
+	salt, key          []byte
+}
+
+// Hash meng-hash password dengan salt acak
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Identifies mengenali string PHC Argon2id
+func (h *Argon2idHasher) Identifies(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+// Verify menghitung ulang hash dengan salt dan parameter dari string PHC lalu membandingkannya
+func (h *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	params, err := parseArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+	return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+}
+
+// NeedsRehash memeriksa apakah parameter hash tersimpan lebih lemah dari parameter hasher
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, err := parseArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.memory < h.Memory ||
+		params.iterations < h.Iterations ||
+		params.parallelism != h.Parallelism ||
+		uint32(len(params.salt)) < h.SaltLength ||
+		uint32(len(params.key)) < h.KeyLength
+}
+
+// parseArgon2id mem-parsing string PHC Argon2id
+func parseArgon2id(encoded string) (*argon2idParams, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+
+	params := &argon2idParams{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return nil, fmt.Errorf("invalid argon2 parameters %q", parts[3])
+	}
+	if params.memory == 0 || params.iterations == 0 || params.parallelism == 0 {
+		return nil, fmt.Errorf("invalid argon2 parameters %q", parts[3])
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(params.key) == 0 {
+		return nil, errors.New("invalid argon2 hash")
+	}
+	return params, nil
+}
+
+// BcryptHasher meng-hash password dengan bcrypt. Hash bcrypt lama tetap bisa diverifikasi
+// setelah algoritma default diganti ke Argon2id.
+type BcryptHasher struct {
+	Cost int
+}
+
+// Hash meng-hash password dengan bcrypt
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Identifies mengenali hash bcrypt ($2a$, $2b$, $2y$)
+func (h *BcryptHasher) Identifies(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// Verify mencocokkan password dengan hash bcrypt
+func (h *BcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// NeedsRehash memeriksa apakah cost hash tersimpan lebih rendah dari cost hasher
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < h.Cost
+}
+
+var (
+	passwordHasherOnce sync.Once
+	passwordHasher     PasswordHasher   // hasher untuk hash baru
+	passwordHashers    []PasswordHasher // semua hasher yang bisa memverifikasi hash tersimpan
+)
+
+// DefaultPasswordHasher mengembalikan hasher untuk hash baru sesuai PASSWORD_HASH_ALGORITHM (argon2id atau bcrypt)
+func DefaultPasswordHasher() PasswordHasher {
+	passwordHasherOnce.Do(func() {
+		argon := &Argon2idHasher{
+			Memory:      uint32(getEnvInt("ARGON2_MEMORY_KIB", 64*1024)),
+			Iterations:  uint32(getEnvInt("ARGON2_ITERATIONS", 3)),
+			Parallelism: uint8(getEnvInt("ARGON2_PARALLELISM", 2)),
+			SaltLength:  16,
+			KeyLength:   32,
+		}
+		bcryptHasher := &BcryptHasher{Cost: getEnvInt("BCRYPT_COST", bcrypt.DefaultCost)}
+
+		passwordHashers = []PasswordHasher{argon, bcryptHasher}
+		switch algorithm := getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"); algorithm {
+		case "bcrypt":
+			passwordHasher = bcryptHasher
+		case "argon2id":
+			passwordHasher = argon
+		default:
+			log.Printf("Unknown PASSWORD_HASH_ALGORITHM %q, using argon2id", algorithm)
+			passwordHasher = argon
+		}
+	})
+	return passwordHasher
+}
+
+// SetPasswordHasher mengganti hasher default; hasher lain yang terdaftar tetap dipakai untuk verifikasi
+func SetPasswordHasher(h PasswordHasher) {
+	DefaultPasswordHasher()
+	passwordHasher = h
+	passwordHashers = append([]PasswordHasher{h}, passwordHashers...)
+}
+
+// HashPassword meng-hash password dengan hasher default
+func HashPassword(password string) (string, error) {
+	return DefaultPasswordHasher().Hash(password)
+}
+
+// VerifyPassword mencocokkan password dengan hash tersimpan memakai hasher yang mengenali format hash itu.
+// needsRehash bernilai true jika password cocok tetapi hash dibuat dengan algoritma lain atau parameter
+// yang lebih lemah dari hasher default, sehingga sebaiknya di-hash ulang.
+func VerifyPassword(password, encoded string) (ok bool, needsRehash bool, err error) {
+	active := DefaultPasswordHasher()
+	for _, hasher := range passwordHashers {
+		if !hasher.Identifies(encoded) {
+			continue
+		}
+		ok, err := hasher.Verify(password, encoded)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		return true, hasher != active || active.NeedsRehash(encoded), nil
+	}
+	return false, false, ErrUnknownPasswordHash
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// VerifyDummyPassword memverifikasi password terhadap hash tetap dari hasher default lalu membuang hasilnya,
+// agar login untuk akun yang tidak ada (atau tanpa password) memakan waktu yang sama dengan akun yang ada
+func VerifyDummyPassword(password string) {
+	hasher := DefaultPasswordHasher()
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = hasher.Hash("dummy password for constant-time login")
+	})
+	hasher.Verify(password, dummyHash)
+}
+
+// getEnvInt mendapatkan nilai integer dari environment variable atau menggunakan nilai default
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(getEnv(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
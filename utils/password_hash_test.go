@@ -0,0 +1,114 @@
+// utils/password_hash_test.go
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+// testArgon2idHasher memakai parameter kecil agar test tetap cepat
+func testArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+}
+
+func TestParseArgon2id(t *testing.T) {
+	const salt = "c29tZXNhbHRzb21lc2FsdA"
+	const key = "aGFzaGhhc2hoYXNoaGFzaGhhc2hoYXNoaGFzaGhhc2g"
+
+	tests := []struct {
+		name        string
+		encoded     string
+		wantUnknown bool
+		wantErr     bool
+		memory      uint32
+		iterations  uint32
+		parallelism uint8
+	}{
+		{name: "valid", encoded: "$argon2id$v=19$m=65536,t=3,p=2$" + salt + "$" + key, memory: 65536, iterations: 3, parallelism: 2},
+		{name: "bcrypt hash", encoded: "$2a$10$abcdefghijklmnopqrstuv", wantUnknown: true},
+		{name: "argon2i variant", encoded: "$argon2i$v=19$m=65536,t=3,p=2$" + salt + "$" + key, wantUnknown: true},
+		{name: "missing hash segment", encoded: "$argon2id$v=19$m=65536,t=3,p=2$" + salt, wantUnknown: true},
+		{name: "extra segment", encoded: "$argon2id$v=19$m=65536,t=3,p=2$" + salt + "$" + key + "$x", wantUnknown: true},
+		{name: "unsupported version", encoded: "$argon2id$v=16$m=65536,t=3,p=2$" + salt + "$" + key, wantErr: true},
+		{name: "malformed version", encoded: "$argon2id$19$m=65536,t=3,p=2$" + salt + "$" + key, wantErr: true},
+		{name: "malformed parameters", encoded: "$argon2id$v=19$t=3,m=65536,p=2$" + salt + "$" + key, wantErr: true},
+		{name: "zero memory", encoded: "$argon2id$v=19$m=0,t=3,p=2$" + salt + "$" + key, wantErr: true},
+		{name: "zero iterations", encoded: "$argon2id$v=19$m=65536,t=0,p=2$" + salt + "$" + key, wantErr: true},
+		{name: "parallelism overflow", encoded: "$argon2id$v=19$m=65536,t=3,p=256$" + salt + "$" + key, wantErr: true},
+		{name: "invalid salt encoding", encoded: "$argon2id$v=19$m=65536,t=3,p=2$not*base64$" + key, wantErr: true},
+		{name: "padded salt", encoded: "$argon2id$v=19$m=65536,t=3,p=2$" + salt + "==$" + key, wantErr: true},
+		{name: "empty hash", encoded: "$argon2id$v=19$m=65536,t=3,p=2$" + salt + "$", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := parseArgon2id(tt.encoded)
+			switch {
+			case tt.wantUnknown:
+				if !errors.Is(err, ErrUnknownPasswordHash) {
+					t.Fatalf("parseArgon2id() error = %v, want ErrUnknownPasswordHash", err)
+				}
+			case tt.wantErr:
+				if err == nil {
+					t.Fatalf("parseArgon2id() = %+v, want error", params)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("parseArgon2id() error = %v", err)
+				}
+				if params.memory != tt.memory || params.iterations != tt.iterations || params.parallelism != tt.parallelism {
+					t.Errorf("parseArgon2id() = m=%d,t=%d,p=%d, want m=%d,t=%d,p=%d",
+						params.memory, params.iterations, params.parallelism, tt.memory, tt.iterations, tt.parallelism)
+				}
+				if len(params.salt) != 16 || len(params.key) != 32 {
+					t.Errorf("parseArgon2id() salt/key length = %d/%d, want 16/32", len(params.salt), len(params.key))
+				}
+			}
+		})
+	}
+}
+
+func TestArgon2idHasher(t *testing.T) {
+	hasher := testArgon2idHasher()
+	encoded, err := hasher.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{"matching password", "correct horse battery staple", true},
+		{"wrong password", "correct horse battery stapler", false},
+		{"empty password", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := hasher.Verify(tt.password, encoded)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if ok != tt.want {
+				t.Errorf("Verify() = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+
+	if !hasher.Identifies(encoded) {
+		t.Errorf("Identifies(%q) = false, want true", encoded)
+	}
+	if hasher.NeedsRehash(encoded) {
+		t.Errorf("NeedsRehash() = true for hash with current parameters")
+	}
+
+	stronger := testArgon2idHasher()
+	stronger.Iterations = 2
+	if !stronger.NeedsRehash(encoded) {
+		t.Errorf("NeedsRehash() = false for hash with fewer iterations")
+	}
+	if !hasher.NeedsRehash("$argon2id$v=19$garbage") {
+		t.Errorf("NeedsRehash() = false for malformed hash")
+	}
+}
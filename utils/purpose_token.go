@@ -0,0 +1,60 @@
+// utils/purpose_token.go
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Tujuan token bertanda tangan selain access token
+const (
+	PurposeEmailVerification = "email_verification"
+	PurposeMFAPending        = "mfa_pending"
+	PurposeMagicLink         = "magic_link"
+	PurposeAccountRestore    = "account_restore"
+)
+
+// PurposeClaim adalah klaim untuk token bertanda tangan sekali pakai (misalnya tautan verifikasi email).
+// TokenUse selalu berisi tujuan token sehingga token ini tidak bisa dipakai sebagai access token.
+type PurposeClaim struct {
+	UserID   uint   `json:"user_id"`
+	TokenUse string `json:"token_use"`
+	Email    string `json:"email,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// GeneratePurposeToken membuat token bertanda tangan untuk tujuan tertentu dengan masa berlaku ttl
+func GeneratePurposeToken(purpose string, userID uint, email string, ttl time.Duration) (string, error) {
+	jti, err := GenerateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := PurposeClaim{
+		UserID:   userID,
+		TokenUse: purpose,
+		Email:    email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+	return signClaims(claims)
+}
+
+// ValidatePurposeToken memvalidasi token dan memastikan tujuannya sesuai
+func ValidatePurposeToken(tokenString, purpose string) (*PurposeClaim, error) {
+	claims := &PurposeClaim{}
+	if err := parseClaims(tokenString, claims); err != nil {
+		return nil, err
+	}
+	if claims.TokenUse != purpose {
+		return nil, fmt.Errorf("token is not valid for %s", purpose)
+	}
+	return claims, nil
+}
@@ -0,0 +1,191 @@
+// utils/webauthn.go
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Algoritma COSE (RFC 9053) yang didukung untuk passkey
+const (
+	COSEAlgES256 = -7
+	COSEAlgEdDSA = -8
+	COSEAlgRS256 = -257
+)
+
+// Flag di authenticator data (WebAuthn Level 2, bagian 6.1)
+const (
+	AuthDataFlagUserPresent       = 0x01
+	AuthDataFlagUserVerified      = 0x04
+	AuthDataFlagBackupEligible    = 0x08
+	AuthDataFlagBackupState       = 0x10
+	AuthDataFlagAttestedCredData  = 0x40
+	AuthDataFlagExtensionDataIncl = 0x80
+)
+
+// ErrInvalidAuthenticatorData dikembalikan jika authenticator data atau kunci COSE tidak valid
+var ErrInvalidAuthenticatorData = errors.New("invalid authenticator data")
+
+// AuthenticatorData adalah hasil parsing authenticator data dari authenticator WebAuthn
+type AuthenticatorData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+
+	// Hanya terisi saat registrasi (flag AT)
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte // kunci publik dalam format COSE (CBOR mentah)
+}
+
+// UserPresent memeriksa flag UP
+func (a *AuthenticatorData) UserPresent() bool {
+	return a.Flags&AuthDataFlagUserPresent != 0
+}
+
+// UserVerified memeriksa flag UV
+func (a *AuthenticatorData) UserVerified() bool {
+	return a.Flags&AuthDataFlagUserVerified != 0
+}
+
+// ParseAuthenticatorData mem-parsing authenticator data: rpIdHash (32) | flags (1) | signCount (4) |
+// attested credential data (opsional) | extensions (opsional)
+func ParseAuthenticatorData(data []byte) (*AuthenticatorData, error) {
+	if len(data) < 37 {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidAuthenticatorData)
+	}
+
+	authData := &AuthenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if authData.Flags&AuthDataFlagAttestedCredData != 0 {
+		// aaguid (16) | credentialIdLength (2) | credentialId | credentialPublicKey (COSE)
+		if len(rest) < 18 {
+			return nil, fmt.Errorf("%w: attested credential data too short", ErrInvalidAuthenticatorData)
+		}
+		authData.AAGUID = rest[:16]
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLength == 0 || idLength > 1023 || len(rest) < idLength {
+			return nil, fmt.Errorf("%w: invalid credential id length", ErrInvalidAuthenticatorData)
+		}
+		authData.CredentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		_, remaining, err := DecodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: credential public key: %v", ErrInvalidAuthenticatorData, err)
+		}
+		authData.PublicKey = rest[:len(rest)-len(remaining)]
+		rest = remaining
+	}
+
+	if authData.Flags&AuthDataFlagExtensionDataIncl != 0 {
+		_, remaining, err := DecodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: extensions: %v", ErrInvalidAuthenticatorData, err)
+		}
+		rest = remaining
+	}
+
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing bytes", ErrInvalidAuthenticatorData)
+	}
+	return authData, nil
+}
+
+// ParseCOSEKey mengubah kunci publik COSE (EC2 P-256, OKP Ed25519 atau RSA) menjadi kunci publik Go
+// beserta algoritmanya
+func ParseCOSEKey(raw []byte) (crypto.PublicKey, int, error) {
+	decoded, rest, err := DecodeCBOR(raw)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrInvalidAuthenticatorData, err)
+	}
+	if len(rest) != 0 {
+		return nil, 0, fmt.Errorf("%w: trailing bytes after COSE key", ErrInvalidAuthenticatorData)
+	}
+	key, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: COSE key is not a map", ErrInvalidAuthenticatorData)
+	}
+
+	kty, _ := key[int64(1)].(int64)
+	alg, _ := key[int64(3)].(int64)
+
+	switch {
+	case kty == 2 && alg == COSEAlgES256: // EC2
+		crv, _ := key[int64(-1)].(int64)
+		x, _ := key[int64(-2)].([]byte)
+		y, _ := key[int64(-3)].([]byte)
+		if crv != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, fmt.Errorf("%w: invalid P-256 key", ErrInvalidAuthenticatorData)
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, 0, fmt.Errorf("%w: point is not on curve", ErrInvalidAuthenticatorData)
+		}
+		return pub, COSEAlgES256, nil
+	case kty == 1 && alg == COSEAlgEdDSA: // OKP
+		crv, _ := key[int64(-1)].(int64)
+		x, _ := key[int64(-2)].([]byte)
+		if crv != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, fmt.Errorf("%w: invalid Ed25519 key", ErrInvalidAuthenticatorData)
+		}
+		return ed25519.PublicKey(x), COSEAlgEdDSA, nil
+	case kty == 3 && alg == COSEAlgRS256: // RSA
+		n, _ := key[int64(-1)].([]byte)
+		e, _ := key[int64(-2)].([]byte)
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) < 256 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, 0, fmt.Errorf("%w: invalid RSA key", ErrInvalidAuthenticatorData)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, COSEAlgRS256, nil
+	default:
+		return nil, 0, fmt.Errorf("%w: unsupported key type %d / algorithm %d", ErrInvalidAuthenticatorData, kty, alg)
+	}
+}
+
+// VerifyCOSESignature memverifikasi tanda tangan assertion WebAuthn atas authenticatorData || SHA-256(clientDataJSON)
+func VerifyCOSESignature(coseKey, authenticatorData, clientDataJSON, signature []byte) error {
+	pub, _, err := ParseCOSEKey(coseKey)
+	if err != nil {
+		return err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := make([]byte, 0, len(authenticatorData)+len(clientDataHash))
+	signed = append(signed, authenticatorData...)
+	signed = append(signed, clientDataHash[:]...)
+
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		if !ecdsa.VerifyASN1(key, digest[:], signature) {
+			return errors.New("invalid signature")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, signed, signature) {
+			return errors.New("invalid signature")
+		}
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported key type %T", pub)
+	}
+	return nil
+}
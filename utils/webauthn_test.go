@@ -0,0 +1,228 @@
+// utils/webauthn_test.go
+package utils
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// coseEC2Key menyusun kunci COSE EC2 P-256 dari koordinat 32 byte
+func coseEC2Key(crv byte, x, y []byte) []byte {
+	key := []byte{0xa5, 0x01, 0x02, 0x03, 0x26, 0x20, crv, 0x21, 0x58, byte(len(x))}
+	key = append(key, x...)
+	key = append(key, 0x22, 0x58, byte(len(y)))
+	return append(key, y...)
+}
+
+// coseEd25519Key menyusun kunci COSE OKP Ed25519
+func coseEd25519Key(x []byte) []byte {
+	key := []byte{0xa4, 0x01, 0x01, 0x03, 0x27, 0x20, 0x06, 0x21, 0x58, byte(len(x))}
+	return append(key, x...)
+}
+
+// testAuthenticatorData menyusun authenticator data dengan flag dan data tambahan tertentu
+func testAuthenticatorData(flags byte, signCount uint32, extra ...[]byte) []byte {
+	data := make([]byte, 37)
+	copy(data, bytes.Repeat([]byte{0xaa}, 32))
+	data[32] = flags
+	binary.BigEndian.PutUint32(data[33:37], signCount)
+	for _, part := range extra {
+		data = append(data, part...)
+	}
+	return data
+}
+
+// attestedCredential menyusun attested credential data: aaguid | panjang id | id | kunci COSE
+func attestedCredential(id, coseKey []byte) []byte {
+	data := make([]byte, 18)
+	binary.BigEndian.PutUint16(data[16:18], uint16(len(id)))
+	data = append(data, id...)
+	return append(data, coseKey...)
+}
+
+func TestParseAuthenticatorData(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coseKey := coseEC2Key(1, ecKey.X.FillBytes(make([]byte, 32)), ecKey.Y.FillBytes(make([]byte, 32)))
+	credentialID := []byte{0x01, 0x02, 0x03, 0x04}
+	extensions := []byte{0xa1, 0x63, 'f', 'o', 'o', 0xf5}
+
+	tests := []struct {
+		name      string
+		data      []byte
+		signCount uint32
+		credID    []byte
+		publicKey []byte
+		wantErr   bool
+	}{
+		{name: "assertion", data: testAuthenticatorData(AuthDataFlagUserPresent, 7), signCount: 7},
+		{
+			name:      "attested credential",
+			data:      testAuthenticatorData(AuthDataFlagUserPresent|AuthDataFlagAttestedCredData, 0, attestedCredential(credentialID, coseKey)),
+			credID:    credentialID,
+			publicKey: coseKey,
+		},
+		{
+			name:      "attested credential with extensions",
+			data:      testAuthenticatorData(AuthDataFlagAttestedCredData|AuthDataFlagExtensionDataIncl, 1, attestedCredential(credentialID, coseKey), extensions),
+			signCount: 1,
+			credID:    credentialID,
+			publicKey: coseKey,
+		},
+		{name: "too short", data: make([]byte, 36), wantErr: true},
+		{name: "trailing bytes", data: testAuthenticatorData(0, 0, []byte{0x00}), wantErr: true},
+		{name: "attested data too short", data: testAuthenticatorData(AuthDataFlagAttestedCredData, 0, make([]byte, 17)), wantErr: true},
+		{name: "empty credential id", data: testAuthenticatorData(AuthDataFlagAttestedCredData, 0, attestedCredential(nil, coseKey)), wantErr: true},
+		{
+			name:    "credential id longer than data",
+			data:    testAuthenticatorData(AuthDataFlagAttestedCredData, 0, attestedCredential(credentialID, nil)[:20]),
+			wantErr: true,
+		},
+		{
+			name:    "truncated public key",
+			data:    testAuthenticatorData(AuthDataFlagAttestedCredData, 0, attestedCredential(credentialID, coseKey[:len(coseKey)-1])),
+			wantErr: true,
+		},
+		{name: "missing extensions", data: testAuthenticatorData(AuthDataFlagExtensionDataIncl, 0), wantErr: true},
+		{name: "malformed extensions", data: testAuthenticatorData(AuthDataFlagExtensionDataIncl, 0, []byte{0xa2, 0x01, 0x02, 0x01, 0x03}), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAuthenticatorData(tt.data)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidAuthenticatorData) {
+					t.Fatalf("ParseAuthenticatorData() error = %v, want ErrInvalidAuthenticatorData", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAuthenticatorData() error = %v", err)
+			}
+			if got.SignCount != tt.signCount {
+				t.Errorf("SignCount = %d, want %d", got.SignCount, tt.signCount)
+			}
+			if !bytes.Equal(got.CredentialID, tt.credID) {
+				t.Errorf("CredentialID = %x, want %x", got.CredentialID, tt.credID)
+			}
+			if !bytes.Equal(got.PublicKey, tt.publicKey) {
+				t.Errorf("PublicKey = %x, want %x", got.PublicKey, tt.publicKey)
+			}
+		})
+	}
+}
+
+func TestParseCOSEKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := ecKey.X.FillBytes(make([]byte, 32))
+	y := ecKey.Y.FillBytes(make([]byte, 32))
+	offCurve := append([]byte(nil), y...)
+	offCurve[31] ^= 0x01
+
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		raw     []byte
+		wantAlg int
+		wantErr bool
+	}{
+		{name: "EC2 P-256", raw: coseEC2Key(1, x, y), wantAlg: COSEAlgES256},
+		{name: "Ed25519", raw: coseEd25519Key(edPub), wantAlg: COSEAlgEdDSA},
+		{name: "not a map", raw: []byte{0x82, 0x01, 0x02}, wantErr: true},
+		{name: "trailing bytes", raw: append(coseEd25519Key(edPub), 0x00), wantErr: true},
+		{name: "truncated", raw: coseEC2Key(1, x, y)[:40], wantErr: true},
+		{name: "wrong curve", raw: coseEC2Key(2, x, y), wantErr: true},
+		{name: "short coordinate", raw: coseEC2Key(1, x[:31], y), wantErr: true},
+		{name: "point not on curve", raw: coseEC2Key(1, x, offCurve), wantErr: true},
+		{name: "short Ed25519 key", raw: coseEd25519Key(edPub[:31]), wantErr: true},
+		{name: "short RSA modulus", raw: []byte{0xa4, 0x01, 0x03, 0x03, 0x39, 0x01, 0x00, 0x20, 0x41, 0x01, 0x21, 0x43, 0x01, 0x00, 0x01}, wantErr: true},
+		{name: "unsupported algorithm", raw: []byte{0xa2, 0x01, 0x02, 0x03, 0x38, 0x22}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, alg, err := ParseCOSEKey(tt.raw)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidAuthenticatorData) {
+					t.Fatalf("ParseCOSEKey() error = %v, want ErrInvalidAuthenticatorData", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCOSEKey() error = %v", err)
+			}
+			if alg != tt.wantAlg {
+				t.Errorf("ParseCOSEKey() alg = %d, want %d", alg, tt.wantAlg)
+			}
+		})
+	}
+}
+
+func TestVerifyCOSESignature(t *testing.T) {
+	authData := testAuthenticatorData(AuthDataFlagUserPresent, 1)
+	clientData := []byte(`{"type":"webauthn.get","challenge":"abc"}`)
+	clientDataHash := sha256.Sum256(clientData)
+	signed := append(append([]byte(nil), authData...), clientDataHash[:]...)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(signed)
+	ecSignature, err := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecCOSE := coseEC2Key(1, ecKey.X.FillBytes(make([]byte, 32)), ecKey.Y.FillBytes(make([]byte, 32)))
+
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edSignature := ed25519.Sign(edPriv, signed)
+	edCOSE := coseEd25519Key(edPub)
+
+	tampered := append([]byte(nil), authData...)
+	tampered[36]++
+
+	tests := []struct {
+		name       string
+		key        []byte
+		authData   []byte
+		clientData []byte
+		signature  []byte
+		wantErr    bool
+	}{
+		{"ES256", ecCOSE, authData, clientData, ecSignature, false},
+		{"EdDSA", edCOSE, authData, clientData, edSignature, false},
+		{"ES256 tampered authenticator data", ecCOSE, tampered, clientData, ecSignature, true},
+		{"EdDSA tampered client data", edCOSE, authData, []byte(`{}`), edSignature, true},
+		{"signature from another key", edCOSE, authData, clientData, ecSignature, true},
+		{"invalid key", []byte{0xa0}, authData, clientData, edSignature, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyCOSESignature(tt.key, tt.authData, tt.clientData, tt.signature)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyCOSESignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}